@@ -0,0 +1,132 @@
+package widelogger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AdaptiveOptions configures an AdaptiveSampler.
+type AdaptiveOptions struct {
+	// Target is the total number of events per second to keep across all keys.
+	Target float64
+	// Window is how often rates are recomputed from the traffic seen in the
+	// previous window. Defaults to 30 seconds.
+	Window time.Duration
+	// Key groups events. Defaults to RouteStatusKey.
+	Key func(ctx context.Context, snap Snapshot) string
+	// MaxKeys bounds the number of keys counted per window. Defaults to 10000;
+	// further keys share a single rate.
+	MaxKeys int
+}
+
+// AdaptiveSampler adjusts per-key sampling rates so that the total number of
+// kept events approaches a throughput target.
+//
+// At the end of each window the budget (Target × Window) is split across keys
+// by volume: keys that need less than an even share keep every event, and what
+// they leave unused is divided among the busier keys, whose rates drop
+// accordingly. Keys not seen in the previous window are kept at rate 1 until
+// the next recomputation.
+type AdaptiveSampler struct {
+	opts AdaptiveOptions
+	now  func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+	rates       map[string]float64
+}
+
+// NewAdaptiveSampler returns an AdaptiveSampler. It panics if opts.Target is not positive.
+func NewAdaptiveSampler(opts AdaptiveOptions) *AdaptiveSampler {
+	if opts.Target <= 0 {
+		panic("widelogger: adaptive sampling target must be positive")
+	}
+	if opts.Window <= 0 {
+		opts.Window = 30 * time.Second
+	}
+	if opts.Key == nil {
+		opts.Key = RouteStatusKey
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 10000
+	}
+	return &AdaptiveSampler{
+		opts:   opts,
+		now:    time.Now,
+		counts: make(map[string]int),
+		rates:  make(map[string]float64),
+	}
+}
+
+func (s *AdaptiveSampler) Sample(ctx context.Context, _ slog.Level, _ string, snap Snapshot) Decision {
+	key := s.opts.Key(ctx, snap)
+	rate := s.observe(key)
+	return Decision{Keep: keepRandom(rate), Rate: rate}
+}
+
+// observe counts an event for key and returns the key's current rate.
+func (s *AdaptiveSampler) observe(key string) float64 {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	} else if elapsed := now.Sub(s.windowStart); elapsed >= s.opts.Window {
+		s.rates = allocateRates(s.counts, s.opts.Target*elapsed.Seconds())
+		s.counts = make(map[string]int, len(s.counts))
+		s.windowStart = now
+	}
+
+	if _, ok := s.counts[key]; !ok && len(s.counts) >= s.opts.MaxKeys {
+		key = ""
+	}
+	s.counts[key]++
+
+	if rate, ok := s.rates[key]; ok {
+		return rate
+	}
+	return 1
+}
+
+// Rates returns the per-key rates computed at the end of the last window.
+func (s *AdaptiveSampler) Rates() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rates := make(map[string]float64, len(s.rates))
+	for k, v := range s.rates {
+		rates[k] = v
+	}
+	return rates
+}
+
+// allocateRates splits budget events across keys, filling the quietest keys first.
+func allocateRates(counts map[string]int, budget float64) map[string]float64 {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return counts[keys[i]] < counts[keys[j]]
+	})
+
+	rates := make(map[string]float64, len(keys))
+	for i, k := range keys {
+		share := budget / float64(len(keys)-i)
+		count := float64(counts[k])
+		if count <= share {
+			rates[k] = 1
+			budget -= count
+			continue
+		}
+		rates[k] = share / count
+		budget -= share
+	}
+	return rates
+}
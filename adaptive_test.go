@@ -0,0 +1,72 @@
+package widelogger
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAllocateRates(t *testing.T) {
+	rates := allocateRates(map[string]int{"rare": 10, "medium": 100, "busy": 10000}, 300)
+
+	if rates["rare"] != 1 || rates["medium"] != 1 {
+		t.Errorf("Expected low-volume keys to keep every event, got %v", rates)
+	}
+	// 190 events of budget remain for the busy key
+	if want := 190.0 / 10000; math.Abs(rates["busy"]-want) > 1e-9 {
+		t.Errorf("Expected busy rate %v, got %v", want, rates["busy"])
+	}
+}
+
+func TestAdaptiveSampler(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewAdaptiveSampler(AdaptiveOptions{Target: 10, Window: 10 * time.Second, Key: tenantKey})
+	s.now = func() time.Time { return now }
+
+	// first window: every key is kept while traffic is measured
+	for i := 0; i < 1000; i++ {
+		if d := sampleKey(s, "busy"); !d.Keep || d.Rate != 1 {
+			t.Fatalf("Expected rate 1 before the first recomputation, got %+v", d)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		sampleKey(s, "rare")
+	}
+
+	now = now.Add(10 * time.Second)
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "tenant", "rare")
+	if d := s.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx)); d.Rate != 1 {
+		t.Errorf("Expected rare key to keep rate 1, got %+v", d)
+	}
+
+	rates := s.Rates()
+	if want := 80.0 / 1000; math.Abs(rates["busy"]-want) > 1e-9 {
+		t.Errorf("Expected busy rate %v, got %v", want, rates["busy"])
+	}
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		if sampleKey(s, "busy").Keep {
+			kept++
+		}
+	}
+	if kept < 40 || kept > 130 {
+		t.Errorf("Expected roughly 80 busy events kept, got %d", kept)
+	}
+
+	if d := sampleKey(s, "new"); d.Rate != 1 {
+		t.Errorf("Expected unseen key to start at rate 1, got %+v", d)
+	}
+}
+
+func TestNewAdaptiveSampler_InvalidTarget(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewAdaptiveSampler should panic on a non-positive target")
+		}
+	}()
+	NewAdaptiveSampler(AdaptiveOptions{})
+}
@@ -0,0 +1,312 @@
+package widelogger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrAsyncClosed is returned by AsyncHandler.Handle after Close has been called.
+var ErrAsyncClosed = errors.New("widelogger: async handler closed")
+
+// OverflowPolicy controls what an AsyncHandler does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for space in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the event being enqueued.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued event to make room.
+	OverflowDropOldest
+	// OverflowDropBelowLevel discards events below AsyncOptions.DropLevel
+	// and blocks for the rest.
+	OverflowDropBelowLevel
+)
+
+// AsyncOptions configures an AsyncHandler.
+type AsyncOptions struct {
+	// QueueSize is the capacity of the queue. Defaults to 1024.
+	QueueSize int
+	// Workers is the number of goroutines writing to the wrapped handler. Defaults to 1.
+	Workers int
+	// Overflow is the policy applied when the queue is full.
+	Overflow OverflowPolicy
+	// DropLevel is the threshold used by OverflowDropBelowLevel.
+	DropLevel slog.Level
+}
+
+// AsyncStats reports counters of an AsyncHandler.
+type AsyncStats struct {
+	Enqueued uint64 // events accepted into the queue
+	Handled  uint64 // events passed to the wrapped handler
+	Dropped  uint64 // events discarded by the overflow policy or after Close
+	Failed   uint64 // events the wrapped handler returned an error for
+	Queued   int    // events currently waiting in the queue
+}
+
+// AsyncHandler is a slog.Handler that hands records to worker goroutines
+// through a bounded queue, so that slow writers do not add to request latency.
+// Call Close during shutdown to drain the queue.
+type AsyncHandler struct {
+	core    *asyncCore
+	handler slog.Handler
+}
+
+type asyncItem struct {
+	ctx     context.Context
+	handler slog.Handler
+	record  slog.Record
+}
+
+type asyncCore struct {
+	queue   chan asyncItem
+	opts    AsyncOptions
+	workers sync.WaitGroup
+
+	// senders hold mu shared; Close takes it exclusively to stop new sends
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	stop      chan struct{}
+	closeOnce sync.Once
+
+	// started counts events accepted by enqueue and resolved those since
+	// handled or dropped; Flush waits for resolved to reach started
+	pendingMu sync.Mutex
+	started   uint64
+	resolved  uint64
+	waiters   []flushWaiter
+
+	enqueued atomic.Uint64
+	handled  atomic.Uint64
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+// NewAsyncHandler wraps h in an AsyncHandler. A nil opts uses the defaults.
+func NewAsyncHandler(h slog.Handler, opts *AsyncOptions) *AsyncHandler {
+	if h == nil {
+		panic("widelogger: handler cannot be nil")
+	}
+
+	var o AsyncOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 1024
+	}
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+
+	c := &asyncCore{
+		queue:   make(chan asyncItem, o.QueueSize),
+		opts:    o,
+		closing: make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	c.workers.Add(o.Workers)
+	for i := 0; i < o.Workers; i++ {
+		go c.run()
+	}
+
+	return &AsyncHandler{core: c, handler: h}
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.core.enqueue(asyncItem{
+		ctx:     context.WithoutCancel(ctx),
+		handler: h.handler,
+		record:  r.Clone(),
+	})
+}
+
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{core: h.core, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{core: h.core, handler: h.handler.WithGroup(name)}
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *AsyncHandler) Stats() AsyncStats {
+	c := h.core
+	return AsyncStats{
+		Enqueued: c.enqueued.Load(),
+		Handled:  c.handled.Load(),
+		Dropped:  c.dropped.Load(),
+		Failed:   c.failed.Load(),
+		Queued:   len(c.queue),
+	}
+}
+
+// Flush blocks until as many events as were enqueued when it was called have
+// been handled or dropped, or ctx is done. Events enqueued afterwards do not
+// delay it.
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	c := h.core
+	c.pendingMu.Lock()
+	if c.resolved >= c.started {
+		c.pendingMu.Unlock()
+		return nil
+	}
+	done := make(chan struct{})
+	c.waiters = append(c.waiters, flushWaiter{target: c.started, done: done})
+	c.pendingMu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting events, drains the queue and waits for the workers
+// to exit or ctx to be done. Events handled after Close return ErrAsyncClosed.
+func (h *AsyncHandler) Close(ctx context.Context) error {
+	c := h.core
+	c.closeOnce.Do(func() {
+		close(c.closing) // release senders blocked on a full queue
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		close(c.stop)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		c.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *asyncCore) enqueue(it asyncItem) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		c.dropped.Add(1)
+		return ErrAsyncClosed
+	}
+
+	c.addPending()
+
+	block := c.opts.Overflow == OverflowBlock ||
+		(c.opts.Overflow == OverflowDropBelowLevel && it.record.Level >= c.opts.DropLevel)
+
+	switch {
+	case block:
+		select {
+		case c.queue <- it:
+		case <-c.closing:
+			c.drop()
+			return ErrAsyncClosed
+		}
+	case c.opts.Overflow == OverflowDropOldest:
+		for {
+			select {
+			case c.queue <- it:
+				c.enqueued.Add(1)
+				return nil
+			default:
+			}
+			select {
+			case <-c.queue:
+				c.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case c.queue <- it:
+		default:
+			c.drop()
+			return nil
+		}
+	}
+
+	c.enqueued.Add(1)
+	return nil
+}
+
+func (c *asyncCore) run() {
+	defer c.workers.Done()
+	for {
+		select {
+		case it := <-c.queue:
+			c.process(it)
+		case <-c.stop:
+			for {
+				select {
+				case it := <-c.queue:
+					c.process(it)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *asyncCore) process(it asyncItem) {
+	defer c.donePending()
+	defer func() {
+		if recover() != nil {
+			c.failed.Add(1)
+		}
+	}()
+
+	if err := it.handler.Handle(it.ctx, it.record); err != nil {
+		c.failed.Add(1)
+	}
+	c.handled.Add(1)
+}
+
+func (c *asyncCore) drop() {
+	c.dropped.Add(1)
+	c.donePending()
+}
+
+// flushWaiter is a Flush call waiting for target events to be resolved.
+type flushWaiter struct {
+	target uint64
+	done   chan struct{}
+}
+
+func (c *asyncCore) addPending() {
+	c.pendingMu.Lock()
+	c.started++
+	c.pendingMu.Unlock()
+}
+
+func (c *asyncCore) donePending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	c.resolved++
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.target <= c.resolved {
+			close(w.done)
+		} else {
+			waiting = append(waiting, w)
+		}
+	}
+	c.waiters = waiting
+}
@@ -0,0 +1,203 @@
+package widelogger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedHandler records messages and blocks in Handle until the gate is opened.
+type gatedHandler struct {
+	mu       sync.Mutex
+	messages []string
+	started  chan struct{}
+	gate     chan struct{}
+}
+
+func newGatedHandler() *gatedHandler {
+	return &gatedHandler{started: make(chan struct{}, 16), gate: make(chan struct{})}
+}
+
+func (h *gatedHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *gatedHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *gatedHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *gatedHandler) Handle(_ context.Context, r slog.Record) error {
+	h.started <- struct{}{}
+	<-h.gate
+	h.mu.Lock()
+	h.messages = append(h.messages, r.Message)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *gatedHandler) got() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+func TestAsyncHandler_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        AsyncOptions
+		thirdLevel  slog.Level
+		want        []string
+		wantDropped uint64
+	}{
+		{"drop newest", AsyncOptions{Overflow: OverflowDropNewest}, slog.LevelInfo, []string{"e1", "e2"}, 1},
+		{"drop oldest", AsyncOptions{Overflow: OverflowDropOldest}, slog.LevelInfo, []string{"e1", "e3"}, 1},
+		{"drop below level", AsyncOptions{Overflow: OverflowDropBelowLevel, DropLevel: slog.LevelWarn}, slog.LevelInfo, []string{"e1", "e2"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newGatedHandler()
+			tt.opts.QueueSize = 1
+			h := NewAsyncHandler(inner, &tt.opts)
+			logger := slog.New(h)
+			ctx := context.Background()
+
+			logger.Info("e1")
+			<-inner.started // the worker holds e1, the queue is empty
+			logger.Info("e2")
+			logger.Log(ctx, tt.thirdLevel, "e3")
+
+			close(inner.gate)
+			if err := h.Flush(ctx); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if got := inner.got(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handled %v, want %v", got, tt.want)
+			}
+			if stats := h.Stats(); stats.Dropped != tt.wantDropped {
+				t.Errorf("Dropped = %d, want %d", stats.Dropped, tt.wantDropped)
+			}
+			_ = h.Close(ctx)
+		})
+	}
+}
+
+func TestAsyncHandler_Block(t *testing.T) {
+	inner := newGatedHandler()
+	h := NewAsyncHandler(inner, &AsyncOptions{QueueSize: 1})
+	logger := slog.New(h)
+
+	logger.Info("e1")
+	<-inner.started
+	logger.Info("e2")
+
+	returned := make(chan struct{})
+	go func() {
+		logger.Info("e3")
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+		t.Fatal("Expected Handle to block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(inner.gate)
+	<-returned
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := inner.got(); !reflect.DeepEqual(got, []string{"e1", "e2", "e3"}) {
+		t.Errorf("Handled %v", got)
+	}
+	if stats := h.Stats(); stats.Enqueued != 3 || stats.Handled != 3 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestAsyncHandler_Close(t *testing.T) {
+	inner := newGatedHandler()
+	close(inner.gate)
+	h := NewAsyncHandler(inner, nil)
+	logger := New(slog.New(h).With("service", "api"))
+
+	ctx := NewContext(context.Background())
+	logger.Info(ctx, "before close")
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := inner.got(); !reflect.DeepEqual(got, []string{"before close"}) {
+		t.Errorf("Expected queued event to be drained on close, got %v", got)
+	}
+
+	if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "after close", 0)); !errors.Is(err, ErrAsyncClosed) {
+		t.Errorf("Expected ErrAsyncClosed, got %v", err)
+	}
+	if stats := h.Stats(); stats.Dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", stats.Dropped)
+	}
+}
+
+func TestAsyncHandler_CloseTimeout(t *testing.T) {
+	inner := newGatedHandler()
+	h := NewAsyncHandler(inner, nil)
+	slog.New(h).Info("stuck")
+	<-inner.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	close(inner.gate)
+}
+
+// slowWriter takes a millisecond per write.
+type slowWriter struct{}
+
+func (slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return len(p), nil
+}
+
+func TestAsyncHandler_FlushUnderLoad(t *testing.T) {
+	h := NewAsyncHandler(slog.NewTextHandler(slowWriter{}, nil), &AsyncOptions{QueueSize: 64})
+	logger := slog.New(h)
+	defer h.Close(context.Background())
+
+	for i := 0; i < 20; i++ {
+		logger.Info("before")
+	}
+
+	stop := make(chan struct{})
+	var producer sync.WaitGroup
+	producer.Add(1)
+	go func() {
+		defer producer.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				logger.Info("steady")
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		producer.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.Flush(ctx); err != nil {
+		t.Fatalf("Expected Flush to return under steady traffic, got %v", err)
+	}
+	if stats := h.Stats(); stats.Handled+stats.Dropped < 20 {
+		t.Errorf("Expected the events enqueued before Flush to be handled, got %+v", stats)
+	}
+}
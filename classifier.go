@@ -0,0 +1,90 @@
+package widelogger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// StatusClientClosedRequest is the non-standard status nginx and others use
+// for requests the client closed before a response was sent. The middleware
+// records it for requests whose context was canceled before the handler wrote
+// a header.
+const StatusClientClosedRequest = 499
+
+// Classifier decides the level and message of the middleware's request event
+// from the response status and a snapshot of the accumulated fields. It is
+// also called for panicking requests, whose snapshot holds the recovered value
+// as the panic field.
+type Classifier func(ctx context.Context, status int, snap Snapshot) (slog.Level, string)
+
+// DefaultClassifier logs requests with errors or a 5xx status at LevelError,
+// requests with warnings or a 4xx status at LevelWarn and the rest at
+// LevelInfo. The message is http_request_completed, with a
+// _with_errors or _with_warnings suffix when the handler recorded any.
+// Panicking requests are logged at LevelError as http_request_panic, except
+// for http.ErrAbortHandler, which is logged at LevelWarn as http_request_aborted.
+func DefaultClassifier(_ context.Context, status int, snap Snapshot) (slog.Level, string) {
+	if recovered, ok := snap.fields["panic"]; ok {
+		if isAbortHandler(recovered) {
+			return slog.LevelWarn, "http_request_aborted"
+		}
+		return slog.LevelError, "http_request_panic"
+	}
+
+	switch {
+	case len(snap.errors) > 0:
+		return slog.LevelError, "http_request_completed_with_errors"
+	case len(snap.warnings) > 0:
+		return slog.LevelWarn, "http_request_completed_with_warnings"
+	case status >= 500:
+		return slog.LevelError, "http_request_completed"
+	case status >= 400:
+		return slog.LevelWarn, "http_request_completed"
+	default:
+		return slog.LevelInfo, "http_request_completed"
+	}
+}
+
+// QuietClientErrorsClassifier is DefaultClassifier with 401, 404 and 499
+// responses logged at LevelInfo, as they are usually expected client behavior.
+var QuietClientErrorsClassifier = StatusLevelClassifier(nil, map[int]slog.Level{
+	401:                       slog.LevelInfo,
+	404:                       slog.LevelInfo,
+	StatusClientClosedRequest: slog.LevelInfo,
+})
+
+// StatusLevelClassifier returns a Classifier that logs the statuses in levels
+// at the mapped level and otherwise defers to base, or DefaultClassifier if
+// base is nil. Requests with errors, warnings or a panic keep the level base
+// gives them.
+func StatusLevelClassifier(base Classifier, levels map[int]slog.Level) Classifier {
+	if base == nil {
+		base = DefaultClassifier
+	}
+	return func(ctx context.Context, status int, snap Snapshot) (slog.Level, string) {
+		level, msg := base(ctx, status, snap)
+		if _, panicked := snap.fields["panic"]; panicked || len(snap.errors) > 0 || len(snap.warnings) > 0 {
+			return level, msg
+		}
+		if l, ok := levels[status]; ok {
+			level = l
+		}
+		return level, msg
+	}
+}
+
+// MessageClassifier returns a Classifier that renames the messages of base, or
+// DefaultClassifier if base is nil, according to names, e.g.
+// {"http_request_completed": "request"}. Other messages are kept.
+func MessageClassifier(base Classifier, names map[string]string) Classifier {
+	if base == nil {
+		base = DefaultClassifier
+	}
+	return func(ctx context.Context, status int, snap Snapshot) (slog.Level, string) {
+		level, msg := base(ctx, status, snap)
+		if name, ok := names[msg]; ok {
+			msg = name
+		}
+		return level, msg
+	}
+}
@@ -0,0 +1,139 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifiers(t *testing.T) {
+	renamed := MessageClassifier(QuietClientErrorsClassifier, map[string]string{
+		"http_request_completed":             "request",
+		"http_request_completed_with_errors": "request_failed",
+	})
+
+	tests := []struct {
+		name       string
+		classifier Classifier
+		status     int
+		withError  bool
+		wantLevel  slog.Level
+		wantMsg    string
+	}{
+		{"default ok", DefaultClassifier, 200, false, slog.LevelInfo, "http_request_completed"},
+		{"default 404", DefaultClassifier, 404, false, slog.LevelWarn, "http_request_completed"},
+		{"default 500", DefaultClassifier, 500, false, slog.LevelError, "http_request_completed"},
+		{"default errors", DefaultClassifier, 200, true, slog.LevelError, "http_request_completed_with_errors"},
+		{"quiet 401", QuietClientErrorsClassifier, 401, false, slog.LevelInfo, "http_request_completed"},
+		{"quiet 404", QuietClientErrorsClassifier, 404, false, slog.LevelInfo, "http_request_completed"},
+		{"quiet 499", QuietClientErrorsClassifier, StatusClientClosedRequest, false, slog.LevelInfo, "http_request_completed"},
+		{"quiet 400", QuietClientErrorsClassifier, 400, false, slog.LevelWarn, "http_request_completed"},
+		{"quiet 404 with errors", QuietClientErrorsClassifier, 404, true, slog.LevelError, "http_request_completed_with_errors"},
+		{"renamed", renamed, 404, false, slog.LevelInfo, "request"},
+		{"renamed errors", renamed, 200, true, slog.LevelError, "request_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background())
+			if tt.withError {
+				AddError(ctx, "failed")
+			}
+			level, msg := tt.classifier(ctx, tt.status, GetSnapshot(ctx))
+			if level != tt.wantLevel || msg != tt.wantMsg {
+				t.Errorf("Expected %v %q, got %v %q", tt.wantLevel, tt.wantMsg, level, msg)
+			}
+		})
+	}
+}
+
+func TestMiddleware_WithClassifier(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	classifier := func(_ context.Context, status int, _ Snapshot) (slog.Level, string) {
+		return slog.LevelInfo, "status_" + http.StatusText(status)
+	}
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}), WithLogger(logger), WithClassifier(classifier), WithSuccessSampling(0))
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if buf.Len() > 0 {
+		t.Errorf("Expected a 404 classified as Info to be sampled, got %q", buf.String())
+	}
+
+	middleware = Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}), WithLogger(logger), WithClassifier(classifier))
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["msg"] != "status_Not Found" || result["level"] != "INFO" {
+		t.Errorf("Expected custom classification, got %v at %v", result["msg"], result["level"])
+	}
+}
+
+func TestMiddleware_ClientClosedRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantLevel  string
+		wantStatus float64
+	}{
+		{"default", nil, "WARN", StatusClientClosedRequest},
+		{"quiet client errors", []Option{WithClassifier(QuietClientErrorsClassifier)}, "INFO", StatusClientClosedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+			opts := append([]Option{WithLogger(logger)}, tt.opts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cancel() // the client disconnects while the handler runs
+				<-r.Context().Done()
+			}), opts...)
+			middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+			var result map[string]any
+			if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+				t.Fatalf("Failed to parse log output: %v", err)
+			}
+			if result["status_code"] != tt.wantStatus || result["level"] != tt.wantLevel {
+				t.Errorf("Expected status %v at %s, got %v at %v", tt.wantStatus, tt.wantLevel, result["status_code"], result["level"])
+			}
+			if result["context_error"] != "context canceled" {
+				t.Errorf("Expected context_error, got %v", result["context_error"])
+			}
+		})
+	}
+}
+
+func TestMiddleware_ClassifierRenamesPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	classifier := MessageClassifier(nil, map[string]string{"http_request_panic": "request_crashed"})
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), WithLogger(logger), WithClassifier(classifier), WithRecovery())
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["msg"] != "request_crashed" || result["level"] != "ERROR" {
+		t.Errorf("Expected renamed panic event at ERROR, got %v at %v", result["msg"], result["level"])
+	}
+}
@@ -0,0 +1,116 @@
+package widelogger
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithTrustedProxies sets the proxies whose Forwarded, X-Forwarded-For and
+// X-Real-IP headers are believed when resolving the client_ip field. Each
+// entry is a CIDR such as "10.0.0.0/8" or a single address. It panics on
+// entries that are neither.
+//
+// The client IP is the right-most address in the forwarding chain that is not
+// a trusted proxy, so that clients cannot spoof it by sending the headers
+// themselves. Without trusted proxies it is the address of the connection.
+func WithTrustedProxies(proxies ...string) Option {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(p)
+			if addrErr != nil {
+				panic("widelogger: invalid trusted proxy " + p)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return func(c *config) {
+		c.trustedProxies = append(c.trustedProxies, prefixes...)
+	}
+}
+
+// clientIP resolves the client address of r, trusting forwarding headers only
+// from the given proxies. It returns "" if the connection address is unknown.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote, ok := parseHop(r.RemoteAddr)
+	if !ok {
+		return ""
+	}
+	if !isTrusted(remote, trusted) {
+		return remote.String()
+	}
+
+	hops := forwardedHops(r.Header)
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			// an obfuscated or malformed hop ends the chain we can follow
+			break
+		}
+		client = addr
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// forwardedHops returns the addresses a request was forwarded for, from the
+// original client to the last proxy, taken from the first header present of
+// Forwarded, X-Forwarded-For and X-Real-IP.
+func forwardedHops(h http.Header) []string {
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		var hops []string
+		for _, v := range values {
+			for _, element := range strings.Split(v, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(key, "for") {
+						hops = append(hops, strings.Trim(value, `"`))
+					}
+				}
+			}
+		}
+		return hops
+	}
+	if values := h.Values("X-Forwarded-For"); len(values) > 0 {
+		var hops []string
+		for _, v := range values {
+			for _, hop := range strings.Split(v, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		return hops
+	}
+	if v := h.Get("X-Real-IP"); v != "" {
+		return []string{strings.TrimSpace(v)}
+	}
+	return nil
+}
+
+// parseHop parses an address with an optional port, as in "192.0.2.1",
+// "192.0.2.1:8080", "2001:db8::1" or "[2001:db8::1]:8080".
+func parseHop(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package widelogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8::/32", "192.0.2.10"}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted remote ignores headers", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.5"},
+		{"x-forwarded-for", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"right-most untrusted hop", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"all trusted", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"single trusted address", "192.0.2.10:1234", map[string]string{"X-Real-IP": "198.51.100.7"}, "198.51.100.7"},
+		{"forwarded", "10.0.0.1:1234", map[string]string{"Forwarded": `for=198.51.100.1;proto=https, for="[2001:db8::1]:4711"`}, "198.51.100.1"},
+		{"forwarded ipv6", "10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db9::1]:4711"`}, "2001:db9::1"},
+		{"forwarded over x-forwarded-for", "10.0.0.1:1234", map[string]string{
+			"Forwarded":       "for=198.51.100.1",
+			"X-Forwarded-For": "198.51.100.2",
+		}, "198.51.100.1"},
+		{"obfuscated hop", "10.0.0.1:1234", map[string]string{"Forwarded": "for=_hidden, for=10.0.0.2"}, "10.0.0.2"},
+		{"malformed remote", "pipe", nil, ""},
+	}
+
+	proxies := WithTrustedProxies(trusted...)
+	cfg := &config{}
+	proxies(cfg)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := clientIP(req, cfg.trustedProxies); got != tt.want {
+				t.Errorf("Expected client IP %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithTrustedProxies_Invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for an invalid proxy")
+		}
+	}()
+	WithTrustedProxies("not-a-cidr")
+}
+
+func TestMiddleware_ClientIP(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithLogger(logger), WithTrustedProxies("10.0.0.0/8"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["client_ip"] != "198.51.100.1" || result["remote_addr"] != "10.1.2.3:5555" {
+		t.Errorf("Expected client_ip and raw remote_addr, got %v and %v", result["client_ip"], result["remote_addr"])
+	}
+}
@@ -0,0 +1,37 @@
+package widelogger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewDebugToken returns a token for the header configured with WithDebugHeader,
+// valid until expires. The token has the form "<unix expiry>.<signature>",
+// where the signature is the hex-encoded HMAC-SHA256 of the expiry with secret.
+func NewDebugToken(secret []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + signDebugToken(secret, exp)
+}
+
+func signDebugToken(secret []byte, exp string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validDebugToken reports whether token was signed with secret and has not expired.
+func validDebugToken(secret []byte, token string, now time.Time) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signDebugToken(secret, exp)))
+}
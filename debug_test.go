@@ -0,0 +1,74 @@
+package widelogger
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidDebugToken(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"valid", NewDebugToken(secret, now.Add(time.Hour)), true},
+		{"expired", NewDebugToken(secret, now.Add(-time.Second)), false},
+		{"wrong secret", NewDebugToken([]byte("other"), now.Add(time.Hour)), false},
+		{"malformed", "not-a-token", false},
+		{"bad expiry", "soon.abcdef", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validDebugToken(secret, tt.token, now); got != tt.want {
+				t.Errorf("validDebugToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware_DebugHeader(t *testing.T) {
+	secret := []byte("s3cret")
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.SetLevel(slog.LevelError)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Debug(r.Context(), "handler_debug")
+		Suppress(r.Context())
+	}), WithLogger(logger), WithSuccessSampling(0), WithDebugHeader("X-Debug-Log", secret))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Debug-Log", NewDebugToken([]byte("forged"), time.Now().Add(time.Hour)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if buf.Len() > 0 {
+		t.Fatalf("Expected invalid token to be ignored, got %q", buf.String())
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Debug-Log", NewDebugToken(secret, time.Now().Add(time.Hour)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !bytes.Contains(buf.Bytes(), []byte("handler_debug")) {
+		t.Errorf("Expected debug events below the logger level, got %q", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("http_request_completed")) || !bytes.Contains(buf.Bytes(), []byte(`"debug_token":"valid"`)) {
+		t.Errorf("Expected request event despite sampling and Suppress, got %q", buf.String())
+	}
+}
+
+func TestWithDebugHeader_EmptySecret(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("WithDebugHeader should panic on an empty secret")
+		}
+	}()
+	WithDebugHeader("X-Debug-Log", nil)
+}
@@ -26,6 +26,10 @@ Instead of logging immediately, you can accumulate issues that will be aggregate
 	widelogger.AddWarning(ctx, "slow query", "duration_ms", 2500)
 	widelogger.AddError(ctx, "database timeout")
 
+Point-in-time events are recorded on an ordered timeline, relative to when the context was created:
+
+	widelogger.AddEvent(ctx, "cache_miss", "key", "user:123")
+
 For more examples, see the examples/ directory or visit:
 https://github.com/mucansever/widelogger
 */
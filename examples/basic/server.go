@@ -43,7 +43,7 @@ func main() {
 	})
 
 	handler := widelogger.Middleware(mux,
-		widelogger.WithLogger(widelogger.New(logger)),
+		widelogger.WithLogger(widelogger.New(logger).WithResource()),
 		widelogger.WithRequestID(), // enable request ID generation/extraction
 		widelogger.WithIncludeRequestHeaders("User-Agent", "X-Request-ID"),
 		widelogger.WithExcludePaths("/health", "/metrics"),
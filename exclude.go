@@ -0,0 +1,117 @@
+package widelogger
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// exclusions decides which requests the middleware does not log.
+type exclusions struct {
+	paths      map[string]bool
+	prefixes   []string
+	globs      []string
+	regexps    []*regexp.Regexp
+	methods    []string
+	userAgents []string
+	funcs      []func(*http.Request) bool
+}
+
+func (e *exclusions) match(r *http.Request) bool {
+	p := r.URL.Path
+	if e.paths[p] {
+		return true
+	}
+	for _, prefix := range e.prefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	for _, glob := range e.globs {
+		if ok, _ := path.Match(glob, p); ok {
+			return true
+		}
+	}
+	for _, re := range e.regexps {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	if slices.ContainsFunc(e.methods, func(m string) bool { return strings.EqualFold(m, r.Method) }) {
+		return true
+	}
+	if ua := r.UserAgent(); ua != "" && slices.ContainsFunc(e.userAgents, func(s string) bool {
+		return strings.Contains(ua, s)
+	}) {
+		return true
+	}
+	for _, fn := range e.funcs {
+		if fn(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithExcludePathPrefixes excludes requests whose path starts with any of prefixes,
+// e.g. "/health/" or "/static/".
+func WithExcludePathPrefixes(prefixes ...string) Option {
+	return func(c *config) {
+		c.exclude.prefixes = append(c.exclude.prefixes, prefixes...)
+	}
+}
+
+// WithExcludePathGlobs excludes requests whose path matches any of the
+// path.Match patterns, e.g. "/static/*" or "/v*/health". It panics on a
+// malformed pattern.
+func WithExcludePathGlobs(patterns ...string) Option {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			panic("widelogger: invalid exclude pattern " + p)
+		}
+	}
+	return func(c *config) {
+		c.exclude.globs = append(c.exclude.globs, patterns...)
+	}
+}
+
+// WithExcludePathRegexps excludes requests whose path matches any of res.
+func WithExcludePathRegexps(res ...*regexp.Regexp) Option {
+	return func(c *config) {
+		c.exclude.regexps = append(c.exclude.regexps, res...)
+	}
+}
+
+// WithExcludeMethods excludes requests with any of methods, e.g. "OPTIONS".
+func WithExcludeMethods(methods ...string) Option {
+	return func(c *config) {
+		c.exclude.methods = append(c.exclude.methods, methods...)
+	}
+}
+
+// WithExcludeUserAgents excludes requests whose User-Agent contains any of
+// substrings, e.g. "kube-probe".
+func WithExcludeUserAgents(substrings ...string) Option {
+	return func(c *config) {
+		c.exclude.userAgents = append(c.exclude.userAgents, substrings...)
+	}
+}
+
+// WithExcludeFunc excludes requests for which fn returns true.
+func WithExcludeFunc(fn func(*http.Request) bool) Option {
+	return func(c *config) {
+		c.exclude.funcs = append(c.exclude.funcs, fn)
+	}
+}
+
+// WithLogExcludedOnFailure logs excluded requests that the Classifier puts at
+// LevelWarn or above, by default those ending with errors, warnings or a
+// 4xx/5xx status, instead of skipping them entirely, so that a failing health
+// check still shows up. Successful excluded requests are not logged.
+func WithLogExcludedOnFailure() Option {
+	return func(c *config) {
+		c.logExcludedOnFailure = true
+	}
+}
@@ -0,0 +1,90 @@
+package widelogger
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware_Exclusions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithLogger(logger),
+		WithExcludePaths("/ping"),
+		WithExcludePathPrefixes("/health/"),
+		WithExcludePathGlobs("/static/*"),
+		WithExcludePathRegexps(regexp.MustCompile(`^/v\d+/metrics$`)),
+		WithExcludeMethods("options"),
+		WithExcludeUserAgents("kube-probe"),
+		WithExcludeFunc(func(r *http.Request) bool { return r.Header.Get("X-Synthetic") != "" }),
+	)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		headers   map[string]string
+		shouldLog bool
+	}{
+		{"exact", "GET", "/ping", nil, false},
+		{"prefix", "GET", "/health/live", nil, false},
+		{"glob", "GET", "/static/app.js", nil, false},
+		{"glob does not cross segments", "GET", "/static/js/app.js", nil, true},
+		{"regexp", "GET", "/v2/metrics", nil, false},
+		{"method", "OPTIONS", "/api/users", nil, false},
+		{"user agent", "GET", "/api/users", map[string]string{"User-Agent": "kube-probe/1.29"}, false},
+		{"predicate", "GET", "/api/users", map[string]string{"X-Synthetic": "1"}, false},
+		{"not excluded", "GET", "/api/users", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+			if hasLog := buf.Len() > 0; hasLog != tt.shouldLog {
+				t.Errorf("Expected log=%v, got log=%v", tt.shouldLog, hasLog)
+			}
+		})
+	}
+}
+
+func TestMiddleware_LogExcludedOnFailure(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	status := http.StatusOK
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}), WithLogger(logger), WithExcludePathPrefixes("/health"), WithLogExcludedOnFailure())
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if buf.Len() > 0 {
+		t.Errorf("Expected healthy check not to be logged, got %q", buf.String())
+	}
+
+	status = http.StatusServiceUnavailable
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if !strings.Contains(buf.String(), `"status_code":503`) {
+		t.Errorf("Expected failing check to be logged, got %q", buf.String())
+	}
+}
+
+func TestWithExcludePathGlobs_Invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for a malformed pattern")
+		}
+	}()
+	WithExcludePathGlobs("/static/[")
+}
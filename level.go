@@ -0,0 +1,285 @@
+package widelogger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type pathContextKey struct{}
+
+// levelControl holds the runtime-adjustable minimum level of a Logger.
+// It is shared by all Loggers derived from the same New call.
+type levelControl struct {
+	level slog.LevelVar
+
+	mu        sync.Mutex
+	gen       uint64
+	revert    *time.Timer
+	base      slog.Level // level to revert to while revert is pending
+	routes    map[string]routeLevel
+	hasRoutes atomic.Bool
+}
+
+type routeLevel struct {
+	level   slog.Level
+	expires time.Time
+}
+
+func newLevelControl(initial slog.Level) *levelControl {
+	lc := &levelControl{routes: make(map[string]routeLevel)}
+	lc.level.Set(initial)
+	return lc
+}
+
+// handlerMinLevel returns the lowest standard level enabled by h, so that a new
+// Logger does not filter anything the handler itself would have accepted.
+func handlerMinLevel(h slog.Handler) slog.Level {
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+		if h.Enabled(context.Background(), level) {
+			return level
+		}
+	}
+	return slog.LevelError
+}
+
+func (lc *levelControl) set(level slog.Level, d time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.gen++
+	// extending a timed override keeps reverting to the level it replaced
+	if lc.revert != nil {
+		lc.revert.Stop()
+		lc.revert = nil
+	} else {
+		lc.base = lc.level.Level()
+	}
+
+	lc.level.Set(level)
+
+	if d > 0 {
+		gen := lc.gen
+		lc.revert = time.AfterFunc(d, func() {
+			lc.mu.Lock()
+			defer lc.mu.Unlock()
+			if lc.gen == gen {
+				lc.level.Set(lc.base)
+				lc.revert = nil
+			}
+		})
+	}
+}
+
+func (lc *levelControl) setRoute(prefix string, level slog.Level, d time.Duration) {
+	rl := routeLevel{level: level}
+	if d > 0 {
+		rl.expires = time.Now().Add(d)
+	}
+	lc.mu.Lock()
+	lc.routes[prefix] = rl
+	lc.hasRoutes.Store(true)
+	lc.mu.Unlock()
+}
+
+func (lc *levelControl) clearRoute(prefix string) {
+	lc.mu.Lock()
+	delete(lc.routes, prefix)
+	lc.hasRoutes.Store(len(lc.routes) > 0)
+	lc.mu.Unlock()
+}
+
+// levelFor returns the minimum level for path. The longest matching,
+// unexpired route prefix wins; otherwise the global level applies.
+func (lc *levelControl) levelFor(path string) slog.Level {
+	if path == "" || !lc.hasRoutes.Load() {
+		return lc.level.Level()
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	now := time.Now()
+	best, found := "", false
+	for prefix, rl := range lc.routes {
+		if !rl.expires.IsZero() && now.After(rl.expires) {
+			delete(lc.routes, prefix)
+			continue
+		}
+		if strings.HasPrefix(path, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	lc.hasRoutes.Store(len(lc.routes) > 0)
+	if found {
+		return lc.routes[best].level
+	}
+	return lc.level.Level()
+}
+
+// Level returns the global minimum level of the logger.
+func (l *Logger) Level() slog.Level {
+	return l.level.level.Level()
+}
+
+// LevelVar returns the slog.LevelVar backing the global minimum level.
+// Setting it directly does not cancel a pending SetLevelFor revert.
+func (l *Logger) LevelVar() *slog.LevelVar {
+	return &l.level.level
+}
+
+// SetLevel sets the global minimum level and cancels any pending auto-revert.
+// The level can only narrow what the underlying handler accepts: setting it
+// below the handler's own minimum has no effect on the levels below that.
+func (l *Logger) SetLevel(level slog.Level) {
+	l.level.set(level, 0)
+}
+
+// SetLevelFor sets the global minimum level and reverts it to the previous
+// value after d. A later SetLevel cancels the revert; a later SetLevelFor
+// replaces it, still reverting to the level in effect before the first one.
+func (l *Logger) SetLevelFor(level slog.Level, d time.Duration) {
+	l.level.set(level, d)
+}
+
+// SetRouteLevel overrides the minimum level for requests whose path starts with prefix.
+// If d is positive the override expires after d; otherwise it stays until cleared.
+func (l *Logger) SetRouteLevel(prefix string, level slog.Level, d time.Duration) {
+	l.level.setRoute(prefix, level, d)
+}
+
+// ClearRouteLevel removes the override for prefix, if any.
+func (l *Logger) ClearRouteLevel(prefix string) {
+	l.level.clearRoute(prefix)
+}
+
+// Enabled reports whether an event at level would be emitted for ctx.
+// Route overrides apply when ctx was created by Middleware, and requests
+// carrying a valid debug header bypass the minimum level.
+func (l *Logger) Enabled(ctx context.Context, level slog.Level) bool {
+	var path string
+	if ctx != nil {
+		path, _ = ctx.Value(pathContextKey{}).(string)
+	}
+	if level < l.level.levelFor(path) {
+		if _, _, debug := logFlags(ctx); !debug {
+			return false
+		}
+	}
+	return l.logger.Enabled(ctx, level)
+}
+
+type levelState struct {
+	Level          string       `json:"level"`
+	EffectiveLevel string       `json:"effective_level"`
+	Routes         []routeState `json:"routes,omitempty"`
+}
+
+type routeState struct {
+	Prefix         string     `json:"prefix"`
+	Level          string     `json:"level"`
+	EffectiveLevel string     `json:"effective_level"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+type levelRequest struct {
+	Level    string `json:"level"`
+	Prefix   string `json:"prefix,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// LevelHandler returns an http.Handler that reads and sets the logger's level.
+//
+// GET returns the global level and active route overrides. PUT or POST with a
+// JSON body such as {"level":"DEBUG","prefix":"/api","duration":"10m"} sets
+// the level, scoped to a route prefix when prefix is given and reverted after
+// duration when it is given. DELETE with a prefix query parameter removes a
+// route override.
+//
+// Each level is reported together with its effective_level, the lowest level
+// actually logged once the underlying handler's own minimum is applied. A
+// handler created with slog.NewJSONHandler(w, nil) never logs Debug, so
+// setting DEBUG reports an effective_level of INFO.
+//
+// The handler performs no authentication; mount it on an internal port.
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var d time.Duration
+			if req.Duration != "" {
+				var err error
+				if d, err = time.ParseDuration(req.Duration); err != nil {
+					http.Error(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if req.Prefix != "" {
+				l.SetRouteLevel(req.Prefix, level, d)
+			} else {
+				l.SetLevelFor(level, d)
+			}
+		case http.MethodDelete:
+			prefix := r.URL.Query().Get("prefix")
+			if prefix == "" {
+				http.Error(w, "prefix query parameter required", http.StatusBadRequest)
+				return
+			}
+			l.ClearRouteLevel(prefix)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(l.levelState())
+	})
+}
+
+func (l *Logger) levelState() levelState {
+	floor := handlerMinLevel(l.logger.Handler())
+	effective := func(level slog.Level) string {
+		return max(level, floor).String()
+	}
+
+	lc := l.level
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	level := lc.level.Level()
+	state := levelState{Level: level.String(), EffectiveLevel: effective(level)}
+	now := time.Now()
+	for prefix, rl := range lc.routes {
+		if !rl.expires.IsZero() && now.After(rl.expires) {
+			continue
+		}
+		rs := routeState{Prefix: prefix, Level: rl.level.String(), EffectiveLevel: effective(rl.level)}
+		if !rl.expires.IsZero() {
+			expires := rl.expires
+			rs.ExpiresAt = &expires
+		}
+		state.Routes = append(state.Routes, rs)
+	}
+	sort.Slice(state.Routes, func(i, j int) bool {
+		return state.Routes[i].Prefix < state.Routes[j].Prefix
+	})
+	return state
+}
@@ -0,0 +1,221 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_DefaultLevelFromHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		handlerLevel slog.Level
+		want         slog.Level
+	}{
+		{"debug handler", slog.LevelDebug, slog.LevelDebug},
+		{"info handler", slog.LevelInfo, slog.LevelInfo},
+		{"error handler", slog.LevelError, slog.LevelError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: tt.handlerLevel})
+			if got := New(slog.New(handler)).Level(); got != tt.want {
+				t.Errorf("Level() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := New(slog.New(handler))
+	logger.SetLevel(slog.LevelInfo)
+
+	ctx := NewContext(context.Background())
+	logger.Debug(ctx, "hidden")
+	if buf.Len() > 0 {
+		t.Fatalf("Expected debug log to be filtered, got %q", buf.String())
+	}
+
+	logger.SetLevel(slog.LevelDebug)
+	logger.Debug(ctx, "visible")
+	if !strings.Contains(buf.String(), "visible") {
+		t.Errorf("Expected debug log after SetLevel, got %q", buf.String())
+	}
+}
+
+func TestLogger_SetLevelFor(t *testing.T) {
+	logger := New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.SetLevel(slog.LevelInfo)
+
+	logger.SetLevelFor(slog.LevelDebug, 20*time.Millisecond)
+	if got := logger.Level(); got != slog.LevelDebug {
+		t.Fatalf("Level() = %v, want DEBUG", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for logger.Level() != slog.LevelInfo && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := logger.Level(); got != slog.LevelInfo {
+		t.Errorf("Level() after revert = %v, want INFO", got)
+	}
+
+	// a permanent SetLevel cancels the pending revert
+	logger.SetLevelFor(slog.LevelDebug, 20*time.Millisecond)
+	logger.SetLevel(slog.LevelWarn)
+	time.Sleep(50 * time.Millisecond)
+	if got := logger.Level(); got != slog.LevelWarn {
+		t.Errorf("Level() = %v, want WARN", got)
+	}
+}
+
+func TestLogger_SetLevelFor_Extend(t *testing.T) {
+	logger := New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.SetLevel(slog.LevelInfo)
+
+	logger.SetLevelFor(slog.LevelDebug, 20*time.Millisecond)
+	logger.SetLevelFor(slog.LevelDebug, 20*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for logger.Level() != slog.LevelInfo && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := logger.Level(); got != slog.LevelInfo {
+		t.Errorf("Level() after extended revert = %v, want INFO", got)
+	}
+}
+
+func TestLogger_RouteLevel(t *testing.T) {
+	logger := New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.SetLevel(slog.LevelWarn)
+	logger.SetRouteLevel("/api", slog.LevelInfo, 0)
+	logger.SetRouteLevel("/api/debug", slog.LevelDebug, 0)
+	logger.SetRouteLevel("/expired", slog.LevelDebug, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		path  string
+		level slog.Level
+		want  bool
+	}{
+		{"/other", slog.LevelInfo, false},
+		{"/api/users", slog.LevelInfo, true},
+		{"/api/users", slog.LevelDebug, false},
+		{"/api/debug/x", slog.LevelDebug, true},
+		{"/expired", slog.LevelDebug, false},
+	}
+
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), pathContextKey{}, tt.path)
+		if got := logger.Enabled(ctx, tt.level); got != tt.want {
+			t.Errorf("Enabled(%s, %v) = %v, want %v", tt.path, tt.level, got, tt.want)
+		}
+	}
+
+	logger.ClearRouteLevel("/api")
+	ctx := context.WithValue(context.Background(), pathContextKey{}, "/api/users")
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		t.Error("Expected route override to be cleared")
+	}
+}
+
+func TestMiddleware_RouteLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.SetLevel(slog.LevelWarn)
+	logger.SetRouteLevel("/api", slog.LevelInfo, 0)
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), WithLogger(logger))
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if buf.Len() > 0 {
+		t.Fatalf("Expected no log for /health, got %q", buf.String())
+	}
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users", nil))
+	if !strings.Contains(buf.String(), "/api/users") {
+		t.Errorf("Expected log for /api/users, got %q", buf.String())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.SetLevel(slog.LevelInfo)
+	handler := logger.LevelHandler()
+
+	do := func(method, target, body string) (*httptest.ResponseRecorder, levelState) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var state levelState
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+		}
+		return rec, state
+	}
+
+	_, state := do("GET", "/", "")
+	if state.Level != "INFO" {
+		t.Errorf("Expected level INFO, got %s", state.Level)
+	}
+
+	_, state = do("PUT", "/", `{"level":"debug"}`)
+	if state.Level != "DEBUG" || logger.Level() != slog.LevelDebug {
+		t.Errorf("Expected level DEBUG, got %s", state.Level)
+	}
+
+	_, state = do("POST", "/", `{"level":"warn","prefix":"/api","duration":"1m"}`)
+	if len(state.Routes) != 1 || state.Routes[0].Prefix != "/api" || state.Routes[0].Level != "WARN" {
+		t.Fatalf("Expected /api route override, got %+v", state.Routes)
+	}
+	if state.Routes[0].ExpiresAt == nil {
+		t.Error("Expected expires_at for timed override")
+	}
+
+	_, state = do("DELETE", "/?prefix=/api", "")
+	if len(state.Routes) != 0 {
+		t.Errorf("Expected no route overrides, got %+v", state.Routes)
+	}
+
+	if rec, _ := do("PUT", "/", `{"level":"loud"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid level, got %d", rec.Code)
+	}
+	if rec, _ := do("PUT", "/", `{"level":"info","duration":"soon"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid duration, got %d", rec.Code)
+	}
+	if rec, _ := do("PATCH", "/", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
+
+func TestLevelHandler_EffectiveLevel(t *testing.T) {
+	logger := New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+	handler := logger.LevelHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/", strings.NewReader(`{"level":"debug"}`)))
+
+	var state levelState
+	if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if state.Level != "DEBUG" || state.EffectiveLevel != "INFO" {
+		t.Errorf("Expected level DEBUG with effective level INFO, got %s and %s", state.Level, state.EffectiveLevel)
+	}
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Expected Debug to stay disabled by the handler")
+	}
+}
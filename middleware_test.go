@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -448,3 +449,143 @@ func TestMiddleware_RequestID_NoPropagation(t *testing.T) {
 		t.Error("Expected no X-Request-ID in response headers when propagation is disabled")
 	}
 }
+
+func TestMiddleware_ContextLogger(t *testing.T) {
+	var defaultBuf, buf bytes.Buffer
+	SetDefaultLogger(slog.New(slog.NewJSONHandler(&defaultBuf, nil)))
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if LoggerFromContext(r.Context()) != logger {
+			t.Error("Expected middleware logger in request context")
+		}
+		Warn(r.Context(), "library_event")
+	}), WithLogger(logger))
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	if !bytes.Contains(buf.Bytes(), []byte("library_event")) {
+		t.Errorf("Expected package-level Warn to use the middleware logger, got %q", buf.String())
+	}
+	if defaultBuf.Len() > 0 {
+		t.Errorf("Expected default logger to be unused, got %q", defaultBuf.String())
+	}
+}
+
+func TestMiddleware_UsageAccounting(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := make(chan struct{})
+		go func() { close(done) }()
+		<-done
+		_ = make([]byte, 1<<20)
+		w.Write([]byte("OK"))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		buf.Reset()
+		Middleware(handler, WithLogger(logger)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		var result map[string]any
+		if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		if _, ok := result["usage"]; ok {
+			t.Error("Expected no usage fields without WithUsageAccounting")
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		buf.Reset()
+		Middleware(handler, WithLogger(logger), WithUsageAccounting()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		var result map[string]any
+		if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		usage, ok := result["usage"].(map[string]any)
+		if !ok {
+			t.Fatalf("Expected usage group, got %v", result["usage"])
+		}
+		if usage["approximate"] != true {
+			t.Error("Expected usage to be flagged as approximate")
+		}
+		for _, key := range []string{"wall_ms", "handler_ms", "write_ms", "goroutines_spawned", "process_alloc_bytes", "process_cpu_ms"} {
+			if _, ok := usage[key]; !ok {
+				t.Errorf("Expected usage.%s, got %v", key, usage)
+			}
+		}
+		if usage["process_alloc_bytes"].(float64) < 1<<20 {
+			t.Errorf("Expected at least 1MiB allocated, got %v", usage["process_alloc_bytes"])
+		}
+	})
+}
+
+func benchmarkMiddleware(b *testing.B, opts ...Option) {
+	logger := New(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}), append([]Option{WithLogger(logger)}, opts...)...)
+
+	req := httptest.NewRequest("GET", "/bench", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	benchmarkMiddleware(b)
+}
+
+func BenchmarkMiddleware_UsageAccounting(b *testing.B) {
+	benchmarkMiddleware(b, WithUsageAccounting())
+}
+
+func TestMiddleware_ForceLogAndSuppress(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		wantLog bool
+	}{
+		{"sampled out", func(w http.ResponseWriter, r *http.Request) {}, false},
+		{"force log", func(w http.ResponseWriter, r *http.Request) { ForceLog(r.Context()) }, true},
+		{"suppress", func(w http.ResponseWriter, r *http.Request) { Suppress(r.Context()) }, false},
+		{"force log wins over suppress", func(w http.ResponseWriter, r *http.Request) {
+			Suppress(r.Context())
+			ForceLog(r.Context())
+		}, true},
+		{"suppress keeps errors", func(w http.ResponseWriter, r *http.Request) {
+			Suppress(r.Context())
+			w.WriteHeader(http.StatusInternalServerError)
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+			Middleware(http.HandlerFunc(tt.handler), WithLogger(logger), WithSuccessSampling(0)).
+				ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			if hasLog := buf.Len() > 0; hasLog != tt.wantLog {
+				t.Errorf("Expected log=%v, got %q", tt.wantLog, buf.String())
+			}
+		})
+	}
+
+	t.Run("suppress without sampler", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+		Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Suppress(r.Context())
+		}), WithLogger(logger)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		if buf.Len() > 0 {
+			t.Errorf("Expected suppressed request not to be logged, got %q", buf.String())
+		}
+	})
+}
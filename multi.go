@@ -0,0 +1,181 @@
+package widelogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand/v2"
+)
+
+// Sink is one destination of a Logger created with NewMulti.
+type Sink struct {
+	// Name identifies the sink in errors.
+	Name string
+	// Handler receives the events accepted by this sink.
+	Handler slog.Handler
+	// Level is the minimum level for this sink. Nil defers to Handler.
+	Level slog.Leveler
+	// SampleRate is the fraction of events kept, in (0, 1]. Zero keeps every event.
+	SampleRate float64
+	// Allow, if non-empty, restricts the event to these top-level fields.
+	Allow []string
+	// Deny removes these top-level fields from the event.
+	Deny []string
+	// OnError, if set, is called when Handler fails or panics.
+	OnError func(sink string, err error)
+}
+
+// NewMulti returns a Logger that fans every event out to all sinks.
+// Each sink filters, samples and handles events independently, so an
+// error or panic in one sink does not prevent delivery to the others.
+func NewMulti(sinks ...Sink) *Logger {
+	h := &multiHandler{sinks: make([]*sinkHandler, 0, len(sinks))}
+	for _, s := range sinks {
+		if s.Handler == nil {
+			panic("widelogger: sink handler cannot be nil")
+		}
+		sh := &sinkHandler{sink: s, handler: s.Handler, groupKept: true}
+		if len(s.Allow) > 0 {
+			sh.allow = make(map[string]bool, len(s.Allow))
+			for _, k := range s.Allow {
+				sh.allow[k] = true
+			}
+		}
+		if len(s.Deny) > 0 {
+			sh.deny = make(map[string]bool, len(s.Deny))
+			for _, k := range s.Deny {
+				sh.deny[k] = true
+			}
+		}
+		h.sinks = append(h.sinks, sh)
+	}
+	return New(slog.New(h))
+}
+
+type multiHandler struct {
+	sinks []*sinkHandler
+}
+
+type sinkHandler struct {
+	sink    Sink
+	handler slog.Handler
+	allow   map[string]bool
+	deny    map[string]bool
+
+	// once a group is open, filtering applies to the outermost group name
+	grouped   bool
+	groupKept bool
+}
+
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, s := range h.sinks {
+		if s.enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, s := range h.sinks {
+		if !s.enabled(ctx, r.Level) || !s.sampled() {
+			continue
+		}
+		if err := s.handle(ctx, r); err != nil {
+			err = fmt.Errorf("widelogger: sink %q: %w", s.sink.Name, err)
+			if s.sink.OnError != nil {
+				s.sink.OnError(s.sink.Name, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	sinks := make([]*sinkHandler, len(h.sinks))
+	for i, s := range h.sinks {
+		c := *s
+		c.handler = s.handler.WithAttrs(s.filterAttrs(attrs))
+		sinks[i] = &c
+	}
+	return &multiHandler{sinks: sinks}
+}
+
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	sinks := make([]*sinkHandler, len(h.sinks))
+	for i, s := range h.sinks {
+		c := *s
+		c.handler = s.handler.WithGroup(name)
+		if !s.grouped {
+			c.grouped = true
+			c.groupKept = s.keep(name)
+		}
+		sinks[i] = &c
+	}
+	return &multiHandler{sinks: sinks}
+}
+
+func (s *sinkHandler) enabled(ctx context.Context, level slog.Level) bool {
+	if s.sink.Level != nil && level < s.sink.Level.Level() {
+		return false
+	}
+	return s.handler.Enabled(ctx, level)
+}
+
+func (s *sinkHandler) sampled() bool {
+	rate := s.sink.SampleRate
+	return rate <= 0 || rate >= 1 || mathrand.Float64() < rate
+}
+
+func (s *sinkHandler) keep(key string) bool {
+	if s.grouped {
+		return s.groupKept
+	}
+	if s.allow != nil && !s.allow[key] {
+		return false
+	}
+	return !s.deny[key]
+}
+
+func (s *sinkHandler) filtering() bool {
+	return s.allow != nil || s.deny != nil
+}
+
+func (s *sinkHandler) filterAttrs(attrs []slog.Attr) []slog.Attr {
+	if !s.filtering() {
+		return attrs
+	}
+	kept := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if s.keep(a.Key) {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+func (s *sinkHandler) handle(ctx context.Context, r slog.Record) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	if s.filtering() {
+		filtered := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			if s.keep(a.Key) {
+				filtered.AddAttrs(a)
+			}
+			return true
+		})
+		r = filtered
+	}
+	return s.handler.Handle(ctx, r)
+}
@@ -0,0 +1,122 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type failingHandler struct {
+	slog.Handler
+	panics bool
+}
+
+func (h failingHandler) Handle(context.Context, slog.Record) error {
+	if h.panics {
+		panic("sink exploded")
+	}
+	return errors.New("disk full")
+}
+
+func TestNewMulti_LevelsAndFilters(t *testing.T) {
+	var all, errorsOnly, filtered bytes.Buffer
+	logger := NewMulti(
+		Sink{Name: "stdout", Handler: slog.NewJSONHandler(&all, nil)},
+		Sink{Name: "errors", Handler: slog.NewJSONHandler(&errorsOnly, nil), Level: slog.LevelError},
+		Sink{
+			Name:    "analytics",
+			Handler: slog.NewJSONHandler(&filtered, nil),
+			Allow:   []string{"path", "user_id", "resource"},
+			Deny:    []string{"user_id"},
+		},
+	)
+	logger = New(logger.logger.With(slog.Group("resource", "service", "api")))
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "path", "/checkout", "user_id", 42, "secret", "x")
+	logger.Info(ctx, "first")
+	logger.Error(ctx, "second")
+
+	if got := strings.Count(all.String(), "\n"); got != 2 {
+		t.Errorf("Expected 2 events in stdout sink, got %d", got)
+	}
+	if strings.Contains(errorsOnly.String(), "first") || !strings.Contains(errorsOnly.String(), "second") {
+		t.Errorf("Expected only the error event in errors sink, got %q", errorsOnly.String())
+	}
+
+	var result map[string]any
+	if err := json.NewDecoder(&filtered).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["path"] != "/checkout" {
+		t.Errorf("Expected allowed field path, got %v", result["path"])
+	}
+	if _, ok := result["user_id"]; ok {
+		t.Error("Expected denied field user_id to be removed")
+	}
+	if _, ok := result["secret"]; ok {
+		t.Error("Expected field outside allowlist to be removed")
+	}
+	if _, ok := result["resource"].(map[string]any); !ok {
+		t.Errorf("Expected allowed group resource, got %v", result["resource"])
+	}
+}
+
+func TestNewMulti_Sampling(t *testing.T) {
+	var kept, dropped bytes.Buffer
+	logger := NewMulti(
+		Sink{Name: "all", Handler: slog.NewJSONHandler(&kept, nil), SampleRate: 1},
+		Sink{Name: "none", Handler: slog.NewJSONHandler(&dropped, nil), SampleRate: 1e-12},
+	)
+
+	ctx := NewContext(context.Background())
+	for i := 0; i < 10; i++ {
+		logger.Info(ctx, "event")
+	}
+
+	if got := strings.Count(kept.String(), "\n"); got != 10 {
+		t.Errorf("Expected 10 events in unsampled sink, got %d", got)
+	}
+	if dropped.Len() > 0 {
+		t.Errorf("Expected sampled sink to drop events, got %q", dropped.String())
+	}
+}
+
+func TestNewMulti_FailingSinkIsolated(t *testing.T) {
+	var buf bytes.Buffer
+	var failures []string
+	onError := func(sink string, err error) {
+		failures = append(failures, sink+": "+err.Error())
+	}
+
+	logger := NewMulti(
+		Sink{Name: "broken", Handler: failingHandler{Handler: slog.NewJSONHandler(&buf, nil)}, OnError: onError},
+		Sink{Name: "panicky", Handler: failingHandler{Handler: slog.NewJSONHandler(&buf, nil), panics: true}, OnError: onError},
+		Sink{Name: "healthy", Handler: slog.NewJSONHandler(&buf, nil)},
+	)
+
+	logger.Info(NewContext(context.Background()), "delivered")
+
+	if !strings.Contains(buf.String(), "delivered") {
+		t.Error("Expected healthy sink to receive the event")
+	}
+	if len(failures) != 2 {
+		t.Fatalf("Expected 2 sink failures, got %v", failures)
+	}
+	if !strings.Contains(failures[0], "disk full") || !strings.Contains(failures[1], "sink exploded") {
+		t.Errorf("Unexpected failures: %v", failures)
+	}
+}
+
+func TestNewMulti_NilHandlerPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewMulti should panic on nil sink handler")
+		}
+	}()
+	NewMulti(Sink{Name: "nil"})
+}
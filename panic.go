@@ -0,0 +1,53 @@
+package widelogger
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// maxStackFrames bounds the frames recorded for a panic.
+const maxStackFrames = 64
+
+// StackFrame is a frame of the stack recorded for a panic.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// panicStack returns the stack of the goroutine that is panicking, starting at
+// the frame that panicked. It must be called from the deferred function that
+// recovered the panic.
+func panicStack() []StackFrame {
+	pcs := make([]uintptr, maxStackFrames+16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []StackFrame
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		// drop the recovering function and the runtime's panic machinery
+		if strings.HasPrefix(frame.Function, "runtime.gopanic") ||
+			strings.HasPrefix(frame.Function, "runtime.panic") ||
+			frame.Function == "runtime.sigpanic" {
+			stack = stack[:0]
+		}
+		if !more {
+			break
+		}
+	}
+	if len(stack) > maxStackFrames {
+		stack = stack[:maxStackFrames]
+	}
+	return stack
+}
+
+// isAbortHandler reports whether recovered is http.ErrAbortHandler, which a
+// handler panics with to abort the response on purpose.
+func isAbortHandler(recovered any) bool {
+	err, ok := recovered.(error)
+	return ok && errors.Is(err, http.ErrAbortHandler)
+}
@@ -0,0 +1,181 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware_PanicFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		panic("boom")
+	}), WithLogger(logger), WithRecovery())
+
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 response, got %d", rec.Code)
+	}
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["msg"] != "http_request_panic" || result["level"] != "ERROR" {
+		t.Errorf("Expected error panic event, got %v at %v", result["msg"], result["level"])
+	}
+	if result["status_code"] != 500.0 {
+		t.Errorf("Expected status_code 500, got %v", result["status_code"])
+	}
+	if d, _ := result["duration_ms"].(float64); d < 5 {
+		t.Errorf("Expected duration_ms of at least 5, got %v", result["duration_ms"])
+	}
+
+	stack, _ := result["panic_stack"].([]any)
+	if len(stack) == 0 {
+		t.Fatalf("Expected panic_stack frames, got %v", result["panic_stack"])
+	}
+	top, _ := stack[0].(map[string]any)
+	if fn, _ := top["function"].(string); !strings.Contains(fn, "TestMiddleware_PanicFields") {
+		t.Errorf("Expected the top frame to be the panicking handler, got %v", top)
+	}
+	if line, _ := top["line"].(float64); line == 0 || top["file"] == "" {
+		t.Errorf("Expected file and line in frame, got %v", top)
+	}
+}
+
+func TestMiddleware_PanicRepanicsWithoutRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), WithLogger(logger))
+
+	func() {
+		defer func() {
+			if recover() != "boom" {
+				t.Error("Expected the panic to propagate")
+			}
+		}()
+		middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	if !strings.Contains(buf.String(), `"status_code":500`) {
+		t.Errorf("Expected status_code 500 in panic event, got %q", buf.String())
+	}
+}
+
+func TestMiddleware_PanicAfterHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}), WithLogger(logger), WithRecovery())
+
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Errorf("Expected the sent response to be left alone, got %d %q", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(buf.String(), `"status_code":202`) {
+		t.Errorf("Expected the sent status in panic event, got %q", buf.String())
+	}
+}
+
+func TestMiddleware_ErrAbortHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), WithLogger(logger), WithRecovery())
+
+	func() {
+		defer func() {
+			if recover() != http.ErrAbortHandler {
+				t.Error("Expected http.ErrAbortHandler to be re-panicked")
+			}
+		}()
+		middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["msg"] != "http_request_aborted" || result["level"] != "WARN" {
+		t.Errorf("Expected warn aborted event, got %v at %v", result["msg"], result["level"])
+	}
+	if _, ok := result["panic_stack"]; ok {
+		t.Error("Expected no stack for an aborted request")
+	}
+}
+
+func TestMiddleware_RecoveryExcludedPath(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	fail := true
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			panic("health check broke")
+		}
+	}), WithLogger(logger), WithRecovery(), WithExcludePaths("/health"))
+
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 response for a panicking excluded request, got %d", rec.Code)
+	}
+	if !strings.Contains(buf.String(), `"msg":"http_request_panic"`) {
+		t.Errorf("Expected the panic to be logged, got %q", buf.String())
+	}
+
+	buf.Reset()
+	fail = false
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if buf.Len() > 0 {
+		t.Errorf("Expected a healthy excluded request not to be logged, got %q", buf.String())
+	}
+}
+
+func TestMiddleware_PanicHandlerWritesStatus(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	var handled any
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), WithLogger(logger), WithPanicHandler(func(_ context.Context, recovered any) {
+		handled = recovered
+	}))
+
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if handled != "boom" {
+		t.Errorf("Expected the panic handler to be called, got %v", handled)
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 response, got %d", rec.Code)
+	}
+	if !strings.Contains(buf.String(), `"status_code":500`) {
+		t.Errorf("Expected status_code 500 in the log, got %q", buf.String())
+	}
+}
@@ -0,0 +1,103 @@
+package widelogger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+const badKey = "!BADKEY"
+
+// Event is a log entry about to be written, as seen by processors.
+// Fields holds the accumulated context fields, warnings, errors and timeline
+// together with the fields passed to the logging call.
+type Event struct {
+	Level   slog.Level
+	Message string
+	Fields  map[string]any
+}
+
+// Processor inspects and modifies an event before it is written.
+// Returning false drops the event.
+type Processor interface {
+	Process(ctx context.Context, e *Event) bool
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(ctx context.Context, e *Event) bool
+
+func (f ProcessorFunc) Process(ctx context.Context, e *Event) bool {
+	return f(ctx, e)
+}
+
+// Use registers processors on the logger. Processors run in registration
+// order on every event, including the middleware's final request event.
+// It is safe to call Use concurrently with logging.
+func (l *Logger) Use(processors ...Processor) {
+	for {
+		old := l.pipeline.processors.Load()
+		var next []Processor
+		if old != nil {
+			next = append(next, *old...)
+		}
+		next = append(next, processors...)
+		if l.pipeline.processors.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+func (l *Logger) loadProcessors() []Processor {
+	if p := l.pipeline.processors.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// newEvent builds an event from the fields accumulated in ctx and the
+// additional key-value pairs or slog.Attrs of a logging call.
+func newEvent(ctx context.Context, level slog.Level, msg string, additionalFields []any) *Event {
+	fields := collectFields(ctx)
+	if fields == nil {
+		fields = make(map[string]any, len(additionalFields)/2)
+	}
+	mergeFields(fields, additionalFields)
+	return &Event{Level: level, Message: msg, Fields: fields}
+}
+
+// mergeFields sets the key-value pairs or slog.Attrs of a logging call in fields.
+func mergeFields(fields map[string]any, additionalFields []any) {
+	for i := 0; i < len(additionalFields); {
+		switch key := additionalFields[i].(type) {
+		case slog.Attr:
+			fields[key.Key] = key.Value.Any()
+			i++
+		case string:
+			if i+1 == len(additionalFields) {
+				fields[badKey] = key
+				i++
+				continue
+			}
+			fields[key] = additionalFields[i+1]
+			i += 2
+		default:
+			fields[badKey] = key
+			i++
+		}
+	}
+}
+
+// attrs returns the event's fields as attributes, sorted by key.
+func (e *Event) attrs() []slog.Attr {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slog.Any(k, e.Fields[k])
+	}
+	return attrs
+}
@@ -0,0 +1,129 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogger_Processors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	var order []string
+	logger.Use(
+		ProcessorFunc(func(ctx context.Context, e *Event) bool {
+			order = append(order, "enrich")
+			if ms, ok := e.Fields["duration_ms"].(int); ok && ms > 1000 {
+				e.Fields["slow"] = true
+			}
+			return true
+		}),
+		ProcessorFunc(func(ctx context.Context, e *Event) bool {
+			order = append(order, "rename")
+			if v, ok := e.Fields["uid"]; ok {
+				delete(e.Fields, "uid")
+				e.Fields["user_id"] = v
+			}
+			e.Message = strings.ToUpper(e.Message)
+			return true
+		}),
+	)
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "duration_ms", 1500, "uid", 7)
+	AddWarning(ctx, "slow query")
+	logger.Info(ctx, "done", "extra", "field")
+
+	if strings.Join(order, ",") != "enrich,rename" {
+		t.Errorf("Expected processors to run in registration order, got %v", order)
+	}
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["slow"] != true {
+		t.Errorf("Expected slow=true, got %v", result["slow"])
+	}
+	if _, ok := result["uid"]; ok || result["user_id"].(float64) != 7 {
+		t.Errorf("Expected uid to be renamed to user_id, got %v", result)
+	}
+	if result["msg"] != "DONE" {
+		t.Errorf("Expected msg=DONE, got %v", result["msg"])
+	}
+	if result["warning_count"].(float64) != 1 || result["extra"] != "field" {
+		t.Errorf("Expected processors to see the full event, got %v", result)
+	}
+}
+
+func TestLogger_ProcessorVeto(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ran := false
+	logger.Use(
+		ProcessorFunc(func(ctx context.Context, e *Event) bool {
+			return e.Fields["synthetic"] != true
+		}),
+		ProcessorFunc(func(ctx context.Context, e *Event) bool {
+			ran = true
+			return true
+		}),
+	)
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "synthetic", true)
+	logger.Info(ctx, "monitor ping")
+
+	if buf.Len() > 0 {
+		t.Errorf("Expected vetoed event to be dropped, got %q", buf.String())
+	}
+	if ran {
+		t.Error("Expected processors after a veto not to run")
+	}
+}
+
+func TestMiddleware_Processors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.Use(ProcessorFunc(func(ctx context.Context, e *Event) bool {
+		e.Fields["processed"] = true
+		return e.Fields["path"] != "/synthetic"
+	}))
+
+	middleware := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), WithLogger(logger))
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/synthetic", nil))
+	if buf.Len() > 0 {
+		t.Fatalf("Expected vetoed request event to be dropped, got %q", buf.String())
+	}
+
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/real", nil))
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["processed"] != true {
+		t.Errorf("Expected processor to run on request event, got %v", result)
+	}
+}
+
+func TestNewEvent_AdditionalFields(t *testing.T) {
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "a", 1, "b", 2)
+
+	e := newEvent(ctx, slog.LevelInfo, "msg", []any{"b", 3, slog.String("c", "x"), 42, "dangling"})
+
+	if e.Fields["a"] != 1 || e.Fields["b"] != 3 || e.Fields["c"] != "x" {
+		t.Errorf("Unexpected fields %v", e.Fields)
+	}
+	if e.Fields[badKey] != "dangling" {
+		t.Errorf("Expected dangling key under %s, got %v", badKey, e.Fields[badKey])
+	}
+}
@@ -0,0 +1,172 @@
+package widelogger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitOptions configures a RateLimitSampler.
+type RateLimitOptions struct {
+	// PerKey is the number of events per second kept for each key.
+	PerKey float64
+	// Burst is the bucket size of each key. Defaults to PerKey rounded up, at least 1.
+	Burst int
+	// Global optionally caps the events per second kept across all keys.
+	Global float64
+	// Floor keeps at least one event per key this often, even when the global
+	// cap is exhausted, so that rare keys stay represented. It never exceeds
+	// the key's own limit. Zero disables it.
+	Floor time.Duration
+	// Key groups events into buckets. Defaults to RouteStatusKey.
+	Key func(ctx context.Context, snap Snapshot) string
+	// MaxKeys bounds the number of tracked keys. Defaults to 10000. Once
+	// reached, keys idle for a minute are evicted and new keys share a bucket.
+	MaxKeys int
+}
+
+// RateLimitSampler keeps at most a fixed number of events per second for each
+// key using token buckets. The reported Rate is the fraction of the key's
+// events kept in the previous one-second window, so that summing 1/Rate over
+// kept events estimates the true count under steady traffic. It is 1 in a key's
+// first window and after a window without events.
+type RateLimitSampler struct {
+	opts RateLimitOptions
+	now  func() time.Time
+
+	mu     sync.Mutex
+	keys   map[string]*keyBucket
+	global tokenBucket
+}
+
+type keyBucket struct {
+	bucket   tokenBucket
+	lastSeen time.Time
+	lastKept time.Time
+
+	windowStart time.Time
+	seen, kept  int
+	// rate is the kept fraction of the previous window
+	rate float64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time elapsed since the last call and
+// consumes one token if available.
+func (b *tokenBucket) take(now time.Time, rate, burst float64) bool {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// NewRateLimitSampler returns a RateLimitSampler. It panics if opts.PerKey is not positive.
+func NewRateLimitSampler(opts RateLimitOptions) *RateLimitSampler {
+	if opts.PerKey <= 0 {
+		panic("widelogger: rate limit must be positive")
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = max(1, int(math.Ceil(opts.PerKey)))
+	}
+	if opts.Key == nil {
+		opts.Key = RouteStatusKey
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 10000
+	}
+	return &RateLimitSampler{
+		opts: opts,
+		now:  time.Now,
+		keys: make(map[string]*keyBucket),
+	}
+}
+
+func (s *RateLimitSampler) Sample(ctx context.Context, _ slog.Level, _ string, snap Snapshot) Decision {
+	key := s.opts.Key(ctx, snap)
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kb := s.bucket(key, now)
+	kb.lastSeen = now
+	if elapsed := now.Sub(kb.windowStart); elapsed >= time.Second {
+		kb.rate = 1
+		if elapsed < 2*time.Second && kb.seen > 0 {
+			// a window in which nothing was kept still represents its events
+			kb.rate = float64(max(kb.kept, 1)) / float64(kb.seen)
+		}
+		kb.windowStart, kb.seen, kb.kept = now, 0, 0
+	}
+	kb.seen++
+
+	keep := kb.bucket.take(now, s.opts.PerKey, float64(s.opts.Burst))
+	if keep && s.opts.Global > 0 && !s.global.take(now, s.opts.Global, math.Max(1, math.Ceil(s.opts.Global))) {
+		// the floor only overrides the global cap, never the key's own limit
+		if s.opts.Floor <= 0 || now.Sub(kb.lastKept) < s.opts.Floor {
+			keep = false
+			kb.bucket.tokens++ // give the key's token back
+		}
+	}
+
+	if keep {
+		kb.lastKept = now
+		kb.kept++
+	}
+	return Decision{Keep: keep, Rate: kb.rate}
+}
+
+func (s *RateLimitSampler) bucket(key string, now time.Time) *keyBucket {
+	if kb, ok := s.keys[key]; ok {
+		return kb
+	}
+	if len(s.keys) >= s.opts.MaxKeys {
+		for k, kb := range s.keys {
+			if now.Sub(kb.lastSeen) > time.Minute {
+				delete(s.keys, k)
+			}
+		}
+		if len(s.keys) >= s.opts.MaxKeys {
+			key = ""
+			if kb, ok := s.keys[key]; ok {
+				return kb
+			}
+		}
+	}
+	kb := &keyBucket{windowStart: now, rate: 1}
+	s.keys[key] = kb
+	return kb
+}
+
+// RouteStatusKey groups events by method, route and status class, e.g.
+// "GET /users/{id} 2xx". The route is the ServeMux pattern when available,
+// and the path otherwise.
+func RouteStatusKey(ctx context.Context, snap Snapshot) string {
+	info := requestInfoFrom(ctx, snap)
+	route := info.pattern
+	if route == "" {
+		route = info.method + " " + info.path
+	}
+
+	status := "-"
+	if v, ok := snap.Field("status_code"); ok {
+		if code, ok := v.(int); ok {
+			status = fmt.Sprintf("%dxx", code/100)
+		}
+	}
+	return route + " " + status
+}
@@ -0,0 +1,157 @@
+package widelogger
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func sampleKey(s Sampler, key string) Decision {
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "tenant", key)
+	return s.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx))
+}
+
+func tenantKey(ctx context.Context, snap Snapshot) string {
+	v, _ := snap.Field("tenant")
+	s, _ := v.(string)
+	return s
+}
+
+func TestRateLimitSampler_PerKey(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewRateLimitSampler(RateLimitOptions{PerKey: 2, Key: tenantKey})
+	s.now = func() time.Time { return now }
+
+	kept := 0
+	for i := 0; i < 10; i++ {
+		if sampleKey(s, "busy").Keep {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Errorf("Expected 2 events kept within the first second, got %d", kept)
+	}
+	if d := sampleKey(s, "quiet"); !d.Keep || d.Rate != 1 {
+		t.Errorf("Expected a separate bucket for another key, got %+v", d)
+	}
+
+	d := sampleKey(s, "busy")
+	if d.Keep || d.Rate != 1 {
+		t.Errorf("Expected busy key to be limited with rate 1 in its first window, got %+v", d)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if !sampleKey(s, "busy").Keep {
+		t.Error("Expected one token to refill after half a second")
+	}
+	if sampleKey(s, "busy").Keep {
+		t.Error("Expected bucket to be empty again")
+	}
+}
+
+func TestRateLimitSampler_WeightedCount(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewRateLimitSampler(RateLimitOptions{PerKey: 1, Key: tenantKey})
+	s.now = func() time.Time { return now }
+
+	const seconds, perSecond = 20, 1000
+	var weighted float64
+	for i := 0; i < seconds*perSecond; i++ {
+		if d := sampleKey(s, "busy"); d.Keep {
+			weighted += 1 / d.Rate
+		}
+		now = now.Add(time.Second / perSecond)
+	}
+
+	// only the first window, whose rate is unknown, is undercounted
+	total := float64(seconds * perSecond)
+	if weighted < 0.9*total || weighted > 1.1*total {
+		t.Errorf("Expected weighted count close to %v, got %v", total, weighted)
+	}
+}
+
+func TestRateLimitSampler_GlobalAndFloor(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewRateLimitSampler(RateLimitOptions{PerKey: 10, Global: 1, Floor: time.Minute, Key: tenantKey})
+	s.now = func() time.Time { return now }
+
+	if !sampleKey(s, "a").Keep {
+		t.Fatal("Expected first event to be kept")
+	}
+	if !sampleKey(s, "rare").Keep {
+		t.Error("Expected floor to keep the first event of a rare key despite the global cap")
+	}
+	if sampleKey(s, "rare").Keep {
+		t.Error("Expected global cap to apply once the floor has been used")
+	}
+
+	now = now.Add(time.Minute)
+	sampleKey(s, "a") // uses the refilled global token
+	if !sampleKey(s, "rare").Keep {
+		t.Error("Expected floor to keep an event again after the interval")
+	}
+}
+
+func TestRateLimitSampler_FloorRespectsPerKey(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewRateLimitSampler(RateLimitOptions{PerKey: 1, Global: 100, Floor: time.Millisecond, Key: tenantKey})
+	s.now = func() time.Time { return now }
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		if sampleKey(s, "busy").Keep {
+			kept++
+		}
+		now = now.Add(5 * time.Millisecond)
+	}
+	if kept != 1 {
+		t.Errorf("Expected the floor not to exceed 1 event per second, got %d kept in 500ms", kept)
+	}
+}
+
+func TestRateLimitSampler_MaxKeys(t *testing.T) {
+	s := NewRateLimitSampler(RateLimitOptions{PerKey: 1, MaxKeys: 2, Key: tenantKey})
+	for _, k := range []string{"a", "b", "c", "d"} {
+		sampleKey(s, k)
+	}
+	if len(s.keys) > 3 {
+		t.Errorf("Expected tracked keys to be bounded, got %d", len(s.keys))
+	}
+	if sampleKey(s, "e").Keep {
+		t.Error("Expected keys beyond MaxKeys to share an exhausted bucket")
+	}
+}
+
+func TestRouteStatusKey(t *testing.T) {
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "method", "POST", "path", "/jobs", "status_code", 202)
+	if key := RouteStatusKey(ctx, GetSnapshot(ctx)); key != "POST /jobs 2xx" {
+		t.Errorf("RouteStatusKey() = %q, want %q", key, "POST /jobs 2xx")
+	}
+
+	var got string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	sampler := SamplerFunc(func(ctx context.Context, level slog.Level, msg string, snap Snapshot) Decision {
+		got = RouteStatusKey(ctx, snap)
+		return Decision{Keep: true, Rate: 1}
+	})
+
+	Middleware(mux, WithSampler(sampler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/7", nil))
+	if got != "GET /orders/{id} 2xx" {
+		t.Errorf("RouteStatusKey() = %q, want %q", got, "GET /orders/{id} 2xx")
+	}
+}
+
+func TestNewRateLimitSampler_InvalidLimit(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewRateLimitSampler should panic on a non-positive limit")
+		}
+	}()
+	NewRateLimitSampler(RateLimitOptions{})
+}
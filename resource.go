@@ -0,0 +1,122 @@
+package widelogger
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"runtime/debug"
+)
+
+// Resource describes the process that emits events.
+type Resource struct {
+	Service   string
+	Version   string
+	Commit    string
+	Hostname  string
+	PID       int
+	GoVersion string
+}
+
+// DetectResource reads the resource of the current process.
+//
+// Service comes from OTEL_SERVICE_NAME or SERVICE_NAME, falling back to the
+// main module path. Version comes from SERVICE_VERSION, falling back to the
+// main module version. Commit comes from the VCS revision stamped into the
+// binary, falling back to GIT_COMMIT.
+func DetectResource() Resource {
+	res := Resource{
+		Service:   firstEnv("OTEL_SERVICE_NAME", "SERVICE_NAME"),
+		Version:   os.Getenv("SERVICE_VERSION"),
+		PID:       os.Getpid(),
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if res.Service == "" && bi.Main.Path != "" {
+			res.Service = path.Base(bi.Main.Path)
+		}
+		if res.Version == "" && bi.Main.Version != "(devel)" {
+			res.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				res.Commit = s.Value
+			}
+		}
+	}
+	if res.Commit == "" {
+		res.Commit = os.Getenv("GIT_COMMIT")
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		res.Hostname = hostname
+	} else {
+		res.Hostname = os.Getenv("HOSTNAME")
+	}
+
+	return res
+}
+
+func firstEnv(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Attrs returns the non-empty fields of r as attributes.
+func (r Resource) Attrs() []slog.Attr {
+	attrs := make([]slog.Attr, 0, 6)
+	for _, a := range []slog.Attr{
+		slog.String("service", r.Service),
+		slog.String("version", r.Version),
+		slog.String("commit", r.Commit),
+		slog.String("hostname", r.Hostname),
+	} {
+		if a.Value.String() != "" {
+			attrs = append(attrs, a)
+		}
+	}
+	if r.PID != 0 {
+		attrs = append(attrs, slog.Int("pid", r.PID))
+	}
+	if r.GoVersion != "" {
+		attrs = append(attrs, slog.String("go_version", r.GoVersion))
+	}
+	return attrs
+}
+
+// WithResource returns a Logger that adds a "resource" group to every event.
+// The group holds the values found by DetectResource; attrs add to them or
+// override detected values with the same key. The returned Logger shares the
+// level, processors, Sampler and sampling summary of l, including changes
+// made later through either Logger.
+func (l *Logger) WithResource(attrs ...slog.Attr) *Logger {
+	merged := DetectResource().Attrs()
+	for _, a := range attrs {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == a.Key {
+				merged[i], replaced = a, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, a)
+		}
+	}
+
+	args := make([]any, len(merged))
+	for i, a := range merged {
+		args[i] = a
+	}
+
+	return &Logger{
+		logger:   l.logger.With(slog.Group("resource", args...)),
+		level:    l.level,
+		pipeline: l.pipeline,
+	}
+}
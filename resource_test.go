@@ -0,0 +1,119 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDetectResource(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("SERVICE_NAME", "checkout")
+	t.Setenv("SERVICE_VERSION", "1.2.3")
+
+	res := DetectResource()
+
+	if res.Service != "checkout" {
+		t.Errorf("Expected service from SERVICE_NAME, got %q", res.Service)
+	}
+	if res.Version != "1.2.3" {
+		t.Errorf("Expected version from SERVICE_VERSION, got %q", res.Version)
+	}
+	if res.PID != os.Getpid() {
+		t.Errorf("Expected pid %d, got %d", os.Getpid(), res.PID)
+	}
+	if res.GoVersion != runtime.Version() {
+		t.Errorf("Expected go_version %q, got %q", runtime.Version(), res.GoVersion)
+	}
+	if hostname, _ := os.Hostname(); res.Hostname != hostname {
+		t.Errorf("Expected hostname %q, got %q", hostname, res.Hostname)
+	}
+
+	t.Setenv("OTEL_SERVICE_NAME", "otel-checkout")
+	if res := DetectResource(); res.Service != "otel-checkout" {
+		t.Errorf("Expected OTEL_SERVICE_NAME to take precedence, got %q", res.Service)
+	}
+}
+
+func TestLogger_WithResource(t *testing.T) {
+	t.Setenv("SERVICE_NAME", "detected")
+
+	var buf bytes.Buffer
+	base := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	base.Use(ProcessorFunc(func(ctx context.Context, e *Event) bool {
+		e.Fields["processed"] = true
+		return true
+	}))
+
+	logger := base.WithResource(slog.String("service", "payments"), slog.String("region", "eu-west-1"))
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "user_id", 1)
+	logger.Info(ctx, "request completed")
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+
+	resource, ok := result["resource"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected resource group, got %v", result["resource"])
+	}
+	if resource["service"] != "payments" {
+		t.Errorf("Expected explicit service to override detected value, got %v", resource["service"])
+	}
+	if resource["region"] != "eu-west-1" {
+		t.Errorf("Expected extra resource attribute, got %v", resource["region"])
+	}
+	if resource["pid"].(float64) != float64(os.Getpid()) || resource["go_version"] != runtime.Version() {
+		t.Errorf("Expected detected pid and go_version, got %v", resource)
+	}
+	if result["processed"] != true {
+		t.Error("Expected processors to carry over to the derived logger")
+	}
+
+	base.SetLevel(slog.LevelError)
+	buf.Reset()
+	logger.Info(ctx, "filtered")
+	if buf.Len() > 0 {
+		t.Error("Expected derived logger to share the level with its parent")
+	}
+}
+
+func TestLogger_WithResource_SharesPipeline(t *testing.T) {
+	var buf bytes.Buffer
+	base := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger := base.WithResource()
+
+	base.SetSampler(RateSampler{Rate: 0})
+	stop := base.StartSamplingSummary(&SamplingSummaryOptions{Interval: time.Hour})
+	logger.Use(ProcessorFunc(func(ctx context.Context, e *Event) bool {
+		e.Fields["processed"] = true
+		return true
+	}))
+
+	logger.Info(NewContext(context.Background()), "dropped")
+	base.Warn(NewContext(context.Background()), "kept")
+	stop()
+
+	dec := json.NewDecoder(&buf)
+	var kept, summary map[string]any
+	if err := dec.Decode(&kept); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if kept["processed"] != true {
+		t.Error("Expected processors added on the derived logger to run on its parent")
+	}
+	if err := dec.Decode(&summary); err != nil {
+		t.Fatalf("Failed to parse summary: %v", err)
+	}
+	if summary["msg"] != "widelogger_sampling_summary" || summary["dropped_total"] != 1.0 {
+		t.Errorf("Expected the derived logger's drop in the parent's summary, got %v", summary)
+	}
+}
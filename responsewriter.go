@@ -0,0 +1,222 @@
+package widelogger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// responseWriter records the status code and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	written    bool
+	bytes      int64
+	hijacked   bool
+
+	// timeWrites enables writeDuration, the time spent in Write calls
+	timeWrites    bool
+	writeDuration time.Duration
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	// informational responses may precede the final status
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		rw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !rw.written {
+		rw.statusCode = code
+		rw.written = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.written {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.timeWrites {
+		n, err := rw.ResponseWriter.Write(b)
+		rw.bytes += int64(n)
+		return n, err
+	}
+	start := time.Now()
+	n, err := rw.ResponseWriter.Write(b)
+	rw.writeDuration += time.Since(start)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *responseWriter) flush() {
+	if !rw.written {
+		rw.statusCode = http.StatusOK
+		rw.written = true
+	}
+	rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (rw *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+	return conn, brw, err
+}
+
+func (rw *responseWriter) readFrom(r io.Reader) (int64, error) {
+	if !rw.written {
+		rw.WriteHeader(http.StatusOK)
+	}
+	start := time.Now()
+	n, err := rw.ResponseWriter.(io.ReaderFrom).ReadFrom(r)
+	if rw.timeWrites {
+		rw.writeDuration += time.Since(start)
+	}
+	rw.bytes += n
+	return n, err
+}
+
+func (rw *responseWriter) push(target string, opts *http.PushOptions) error {
+	return rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type flusher struct{ rw *responseWriter }
+
+func (f flusher) Flush() { f.rw.flush() }
+
+type hijacker struct{ rw *responseWriter }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.rw.hijack() }
+
+type readerFrom struct{ rw *responseWriter }
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) { return r.rw.readFrom(src) }
+
+type pusher struct{ rw *responseWriter }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error { return p.rw.push(target, opts) }
+
+// wrap returns rw as an http.ResponseWriter that implements http.Flusher,
+// http.Hijacker, io.ReaderFrom and http.Pusher exactly when the underlying
+// writer does, so that handlers relying on them keep working.
+func (rw *responseWriter) wrap() http.ResponseWriter {
+	var mask int
+	if _, ok := rw.ResponseWriter.(http.Flusher); ok {
+		mask |= 1
+	}
+	if _, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		mask |= 2
+	}
+	if _, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		mask |= 4
+	}
+	if _, ok := rw.ResponseWriter.(http.Pusher); ok {
+		mask |= 8
+	}
+
+	f, h, r, p := flusher{rw}, hijacker{rw}, readerFrom{rw}, pusher{rw}
+	switch mask {
+	case 1:
+		return struct {
+			*responseWriter
+			flusher
+		}{rw, f}
+	case 2:
+		return struct {
+			*responseWriter
+			hijacker
+		}{rw, h}
+	case 3:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{rw, f, h}
+	case 4:
+		return struct {
+			*responseWriter
+			readerFrom
+		}{rw, r}
+	case 5:
+		return struct {
+			*responseWriter
+			flusher
+			readerFrom
+		}{rw, f, r}
+	case 6:
+		return struct {
+			*responseWriter
+			hijacker
+			readerFrom
+		}{rw, h, r}
+	case 7:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+		}{rw, f, h, r}
+	case 8:
+		return struct {
+			*responseWriter
+			pusher
+		}{rw, p}
+	case 9:
+		return struct {
+			*responseWriter
+			flusher
+			pusher
+		}{rw, f, p}
+	case 10:
+		return struct {
+			*responseWriter
+			hijacker
+			pusher
+		}{rw, h, p}
+	case 11:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+		}{rw, f, h, p}
+	case 12:
+		return struct {
+			*responseWriter
+			readerFrom
+			pusher
+		}{rw, r, p}
+	case 13:
+		return struct {
+			*responseWriter
+			flusher
+			readerFrom
+			pusher
+		}{rw, f, r, p}
+	case 14:
+		return struct {
+			*responseWriter
+			hijacker
+			readerFrom
+			pusher
+		}{rw, h, r, p}
+	case 15:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+			pusher
+		}{rw, f, h, r, p}
+	default:
+		return rw
+	}
+}
@@ -0,0 +1,170 @@
+package widelogger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseWriter_OptionalInterfaces(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		w                                     http.ResponseWriter
+		flusher, hijacker, readerFrom, pusher bool
+	}{
+		{"plain", plainWriter{httptest.NewRecorder()}, false, false, false, false},
+		{"recorder", httptest.NewRecorder(), true, false, false, false},
+		{"all", fullWriter{plainWriter{httptest.NewRecorder()}}, true, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := (&responseWriter{ResponseWriter: tt.w, statusCode: http.StatusOK}).wrap()
+
+			if _, ok := w.(http.Flusher); ok != tt.flusher {
+				t.Errorf("Expected http.Flusher=%v", tt.flusher)
+			}
+			if _, ok := w.(http.Hijacker); ok != tt.hijacker {
+				t.Errorf("Expected http.Hijacker=%v", tt.hijacker)
+			}
+			if _, ok := w.(io.ReaderFrom); ok != tt.readerFrom {
+				t.Errorf("Expected io.ReaderFrom=%v", tt.readerFrom)
+			}
+			if _, ok := w.(http.Pusher); ok != tt.pusher {
+				t.Errorf("Expected http.Pusher=%v", tt.pusher)
+			}
+			if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != tt.w {
+				t.Error("Expected Unwrap to return the underlying writer")
+			}
+		})
+	}
+}
+
+func TestResponseWriter_ReadFromCountsBytes(t *testing.T) {
+	rw := &responseWriter{ResponseWriter: fullWriter{plainWriter{httptest.NewRecorder()}}, statusCode: http.StatusOK}
+	w := rw.wrap()
+
+	n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("Expected 5 bytes copied, got %d, %v", n, err)
+	}
+	w.Write([]byte(" world"))
+
+	if rw.bytes != 11 || !rw.written || rw.statusCode != http.StatusOK {
+		t.Errorf("Expected 11 bytes and status 200, got %d bytes, written=%v, status %d", rw.bytes, rw.written, rw.statusCode)
+	}
+}
+
+func TestResponseWriter_InformationalStatus(t *testing.T) {
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
+	rw.WriteHeader(http.StatusEarlyHints)
+	rw.WriteHeader(http.StatusCreated)
+
+	if rw.statusCode != http.StatusCreated {
+		t.Errorf("Expected status 201 after early hints, got %d", rw.statusCode)
+	}
+}
+
+func TestMiddleware_ResponseFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		body          string
+		wantBytes     float64
+		wantHeader    bool
+		wantReqLength float64
+	}{
+		{"body", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) }, "", 5, true, 0},
+		{"no write", func(w http.ResponseWriter, r *http.Request) {}, "payload", 0, false, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+			Middleware(tt.handler, WithLogger(logger)).
+				ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+
+			var result map[string]any
+			if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+				t.Fatalf("Failed to parse log output: %v", err)
+			}
+			if result["response_bytes"] != tt.wantBytes {
+				t.Errorf("Expected response_bytes=%v, got %v", tt.wantBytes, result["response_bytes"])
+			}
+			if result["header_written"] != tt.wantHeader {
+				t.Errorf("Expected header_written=%v, got %v", tt.wantHeader, result["header_written"])
+			}
+			if result["request_content_length"] != tt.wantReqLength {
+				t.Errorf("Expected request_content_length=%v, got %v", tt.wantReqLength, result["request_content_length"])
+			}
+		})
+	}
+}
+
+func TestMiddleware_PreservesServerInterfaces(t *testing.T) {
+	var buf syncBuffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	srv := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("Expected http.Hijacker to be preserved")
+		}
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Error("Expected io.ReaderFrom to be preserved")
+		}
+		if _, ok := w.(http.Pusher); ok {
+			t.Error("Expected no http.Pusher over HTTP/1.1")
+		}
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("Expected ResponseController to reach the connection, got %v", err)
+		}
+		w.Write([]byte("data: 1\n\n"))
+		if err := rc.Flush(); err != nil {
+			t.Errorf("Expected Flush to succeed, got %v", err)
+		}
+	}), WithLogger(logger)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// plainWriter hides every optional interface of the wrapped writer.
+type plainWriter struct {
+	w http.ResponseWriter
+}
+
+func (p plainWriter) Header() http.Header         { return p.w.Header() }
+func (p plainWriter) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p plainWriter) WriteHeader(code int)        { p.w.WriteHeader(code) }
+
+// fullWriter implements every optional interface.
+type fullWriter struct {
+	plainWriter
+}
+
+func (fullWriter) Flush() {}
+
+func (fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+func (f fullWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(f.plainWriter, r)
+}
+
+func (fullWriter) Push(string, *http.PushOptions) error { return http.ErrNotSupported }
@@ -0,0 +1,355 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordedEvent is a wide event retained by a RingBuffer.
+type RecordedEvent struct {
+	Time    time.Time      `json:"time"`
+	Level   slog.Level     `json:"level"`
+	Message string         `json:"msg"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// RingBufferOptions configures a RingBuffer.
+type RingBufferOptions struct {
+	// Size is the number of events retained per level. Defaults to 1000.
+	Size int
+	// LevelSizes overrides Size for individual levels, e.g. to keep errors longer.
+	// Levels are bucketed as DEBUG, INFO, WARN and ERROR.
+	LevelSizes map[slog.Level]int
+}
+
+// RingQuery selects events from a RingBuffer. Zero fields do not filter.
+type RingQuery struct {
+	// MinLevel keeps events at or above this level.
+	MinLevel slog.Leveler
+	// PathPrefix keeps events whose "path" field starts with this prefix.
+	PathPrefix string
+	// MinDuration keeps events whose "duration_ms" field is at least this long.
+	MinDuration time.Duration
+	// Since and Until bound the event time.
+	Since, Until time.Time
+	// Fields keeps events whose top-level fields equal these values,
+	// compared by their fmt.Sprint representation.
+	Fields map[string]string
+	// Limit caps the number of events returned.
+	Limit int
+}
+
+// RingBuffer is a slog.Handler that keeps the most recent events in memory,
+// with separate retention per level. Use it as a Sink with NewMulti and expose
+// it with QueryHandler to inspect a process without going through the log pipeline.
+type RingBuffer struct {
+	store  *ringStore
+	attrs  []scopedAttr
+	groups []string
+}
+
+type scopedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+type ringStore struct {
+	mu    sync.RWMutex
+	rings map[slog.Level]*ring
+}
+
+type ring struct {
+	events []RecordedEvent
+	next   int
+	full   bool
+}
+
+// NewRingBuffer returns an empty RingBuffer. A nil opts uses the defaults.
+func NewRingBuffer(opts *RingBufferOptions) *RingBuffer {
+	var o RingBufferOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Size <= 0 {
+		o.Size = 1000
+	}
+
+	store := &ringStore{rings: make(map[slog.Level]*ring, 4)}
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		size := o.Size
+		if n, ok := o.LevelSizes[level]; ok {
+			size = n
+		}
+		if size > 0 {
+			store.rings[level] = &ring{events: make([]RecordedEvent, size)}
+		}
+	}
+	return &RingBuffer{store: store}
+}
+
+func levelBucket(level slog.Level) slog.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return slog.LevelDebug
+	case level < slog.LevelWarn:
+		return slog.LevelInfo
+	case level < slog.LevelError:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+func (rb *RingBuffer) Enabled(_ context.Context, level slog.Level) bool {
+	return rb.store.rings[levelBucket(level)] != nil
+}
+
+func (rb *RingBuffer) Handle(_ context.Context, r slog.Record) error {
+	ev := RecordedEvent{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Fields:  make(map[string]any, len(rb.attrs)+r.NumAttrs()),
+	}
+	for _, sa := range rb.attrs {
+		setScoped(ev.Fields, sa.groups, sa.attr)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		setScoped(ev.Fields, rb.groups, a)
+		return true
+	})
+
+	rb.store.mu.Lock()
+	defer rb.store.mu.Unlock()
+
+	rg := rb.store.rings[levelBucket(r.Level)]
+	if rg == nil {
+		return nil
+	}
+	rg.events[rg.next] = ev
+	rg.next++
+	if rg.next == len(rg.events) {
+		rg.next = 0
+		rg.full = true
+	}
+	return nil
+}
+
+func (rb *RingBuffer) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *rb
+	c.attrs = make([]scopedAttr, len(rb.attrs), len(rb.attrs)+len(attrs))
+	copy(c.attrs, rb.attrs)
+	for _, a := range attrs {
+		c.attrs = append(c.attrs, scopedAttr{groups: rb.groups, attr: a})
+	}
+	return &c
+}
+
+func (rb *RingBuffer) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return rb
+	}
+	c := *rb
+	c.groups = append(append([]string(nil), rb.groups...), name)
+	return &c
+}
+
+// setScoped stores a in fields, nested under groups.
+func setScoped(fields map[string]any, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	for _, g := range groups {
+		sub, ok := fields[g].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			fields[g] = sub
+		}
+		fields = sub
+	}
+	if a.Value.Kind() == slog.KindGroup && a.Key == "" {
+		for _, ga := range a.Value.Group() {
+			setScoped(fields, nil, ga)
+		}
+		return
+	}
+	fields[a.Key] = attrValue(a.Value)
+}
+
+// attrValue converts v for storage. Values that cannot be encoded as JSON,
+// such as funcs, channels and NaN, are kept as their fmt.Sprint form so that
+// one event cannot break QueryHandler.
+func attrValue(v slog.Value) any {
+	if v.Kind() != slog.KindGroup {
+		a := v.Any()
+		if k := v.Kind(); k == slog.KindAny || k == slog.KindFloat64 {
+			if _, err := json.Marshal(a); err != nil {
+				return fmt.Sprint(a)
+			}
+		}
+		return a
+	}
+	m := make(map[string]any)
+	for _, a := range v.Group() {
+		setScoped(m, nil, a)
+	}
+	return m
+}
+
+// Query returns the retained events matching q, newest first.
+func (rb *RingBuffer) Query(q RingQuery) []RecordedEvent {
+	rb.store.mu.RLock()
+	var events []RecordedEvent
+	for _, rg := range rb.store.rings {
+		n := rg.next
+		if rg.full {
+			n = len(rg.events)
+		}
+		for i := 0; i < n; i++ {
+			if ev := rg.events[i]; q.matches(ev) {
+				events = append(events, ev)
+			}
+		}
+	}
+	rb.store.mu.RUnlock()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[:q.Limit]
+	}
+	return events
+}
+
+func (q RingQuery) matches(ev RecordedEvent) bool {
+	if q.MinLevel != nil && ev.Level < q.MinLevel.Level() {
+		return false
+	}
+	if !q.Since.IsZero() && ev.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && ev.Time.After(q.Until) {
+		return false
+	}
+	if q.PathPrefix != "" {
+		path, _ := ev.Fields["path"].(string)
+		if !strings.HasPrefix(path, q.PathPrefix) {
+			return false
+		}
+	}
+	if q.MinDuration > 0 {
+		ms, ok := toFloat(ev.Fields["duration_ms"])
+		if !ok || time.Duration(ms*float64(time.Millisecond)) < q.MinDuration {
+			return false
+		}
+	}
+	for k, want := range q.Fields {
+		v, ok := ev.Fields[k]
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// QueryHandler returns an http.Handler that serves matching events as JSON.
+//
+// Supported query parameters are level (minimum level), path (path prefix),
+// min_duration (a Go duration such as 250ms), since and until (RFC 3339),
+// limit, and field=key:value, which may be repeated.
+//
+// The handler performs no authentication; mount it on an internal port.
+func (rb *RingBuffer) QueryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseRingQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		events := rb.Query(q)
+		if events == nil {
+			events = []RecordedEvent{}
+		}
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(events); err != nil {
+			http.Error(w, "encoding events: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+func parseRingQuery(r *http.Request) (RingQuery, error) {
+	values := r.URL.Query()
+	q := RingQuery{PathPrefix: values.Get("path")}
+
+	if s := values.Get("level"); s != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return q, fmt.Errorf("invalid level: %w", err)
+		}
+		q.MinLevel = level
+	}
+	if s := values.Get("min_duration"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid min_duration: %w", err)
+		}
+		q.MinDuration = d
+	}
+	for name, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if s := values.Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*dst = t
+		}
+	}
+	if s := values.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("invalid limit: %q", s)
+		}
+		q.Limit = n
+	}
+	for _, f := range values["field"] {
+		key, value, ok := strings.Cut(f, ":")
+		if !ok || key == "" {
+			return q, fmt.Errorf("invalid field filter %q, want key:value", f)
+		}
+		if q.Fields == nil {
+			q.Fields = make(map[string]string)
+		}
+		q.Fields[key] = value
+	}
+	return q, nil
+}
@@ -0,0 +1,151 @@
+package widelogger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer_Retention(t *testing.T) {
+	rb := NewRingBuffer(&RingBufferOptions{
+		Size:       2,
+		LevelSizes: map[slog.Level]int{slog.LevelError: 5, slog.LevelDebug: 0},
+	})
+	logger := New(slog.New(rb))
+
+	ctx := NewContext(context.Background())
+	for i := 0; i < 4; i++ {
+		logger.Info(ctx, "info", "i", i)
+		logger.Error(ctx, "error", "i", i)
+		logger.Debug(ctx, "debug", "i", i)
+	}
+
+	var infos, errs, debugs int
+	for _, ev := range rb.Query(RingQuery{}) {
+		switch ev.Level {
+		case slog.LevelInfo:
+			infos++
+		case slog.LevelError:
+			errs++
+		case slog.LevelDebug:
+			debugs++
+		}
+	}
+	if infos != 2 || errs != 4 || debugs != 0 {
+		t.Errorf("Expected 2 info, 4 error and 0 debug events, got %d, %d, %d", infos, errs, debugs)
+	}
+
+	newest := rb.Query(RingQuery{MinLevel: slog.LevelInfo, Limit: 1})
+	if len(newest) != 1 || newest[0].Fields["i"] != int64(3) {
+		t.Errorf("Expected newest event first, got %+v", newest)
+	}
+}
+
+func TestRingBuffer_Query(t *testing.T) {
+	rb := NewRingBuffer(nil)
+	logger := New(slog.New(rb).With(slog.Group("resource", "service", "api")))
+
+	emit := func(path string, durationMS int64, plan string, level slog.Level) {
+		ctx := NewContext(context.Background())
+		AddFields(ctx, "path", path, "duration_ms", durationMS, "plan", plan)
+		logger.Log(ctx, level, "http_request_completed")
+	}
+	emit("/api/users", 10, "free", slog.LevelInfo)
+	emit("/api/orders", 900, "enterprise", slog.LevelInfo)
+	emit("/health", 1, "free", slog.LevelWarn)
+
+	tests := []struct {
+		name string
+		q    RingQuery
+		want int
+	}{
+		{"all", RingQuery{}, 3},
+		{"path prefix", RingQuery{PathPrefix: "/api"}, 2},
+		{"min duration", RingQuery{MinDuration: 500 * time.Millisecond}, 1},
+		{"field equality", RingQuery{Fields: map[string]string{"plan": "enterprise"}}, 1},
+		{"min level", RingQuery{MinLevel: slog.LevelWarn}, 1},
+		{"until", RingQuery{Until: time.Now().Add(-time.Hour)}, 0},
+		{"since", RingQuery{Since: time.Now().Add(-time.Hour)}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(rb.Query(tt.q)); got != tt.want {
+				t.Errorf("Query() returned %d events, want %d", got, tt.want)
+			}
+		})
+	}
+
+	ev := rb.Query(RingQuery{Limit: 1})[0]
+	resource, ok := ev.Fields["resource"].(map[string]any)
+	if !ok || resource["service"] != "api" {
+		t.Errorf("Expected grouped resource attributes, got %v", ev.Fields["resource"])
+	}
+}
+
+func TestRingBuffer_QueryHandler(t *testing.T) {
+	rb := NewRingBuffer(nil)
+	logger := New(slog.New(rb))
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "path", "/api/users", "duration_ms", 300, "user_id", "42")
+	logger.Warn(ctx, "http_request_completed")
+
+	rec := httptest.NewRecorder()
+	rb.QueryHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/events?level=warn&path=/api&min_duration=250ms&field=user_id:42&limit=10", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var events []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(events) != 1 || events[0]["level"] != "WARN" {
+		t.Errorf("Expected one WARN event, got %v", events)
+	}
+
+	for _, target := range []string{"/?level=loud", "/?min_duration=long", "/?since=yesterday", "/?limit=-1", "/?field=nocolon"} {
+		rec := httptest.NewRecorder()
+		rb.QueryHandler().ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestRingBuffer_QueryHandler_UnencodableValues(t *testing.T) {
+	rb := NewRingBuffer(nil)
+	logger := New(slog.New(rb))
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "done", make(chan struct{}), "f", func() {}, "ratio", math.NaN())
+	logger.Info(ctx, "bad")
+	logger.Info(NewContext(context.Background()), "good")
+
+	rec := httptest.NewRecorder()
+	rb.QueryHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/events", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var events []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected both events, got %v", events)
+	}
+	fields, _ := events[1]["fields"].(map[string]any)
+	if fields["ratio"] != "NaN" {
+		t.Errorf("Expected NaN stored as a string, got %v", fields["ratio"])
+	}
+	if _, ok := fields["f"].(string); !ok {
+		t.Errorf("Expected func stored as a string, got %v", fields["f"])
+	}
+}
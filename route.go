@@ -0,0 +1,58 @@
+package widelogger
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// RouteFunc returns the route template a request matched, e.g. "/users/:id",
+// or "" if it is unknown.
+type RouteFunc func(r *http.Request) string
+
+// addRouteFields records the route and path parameters of r once the handler
+// has run, so that the pattern set by a ServeMux during routing is known.
+func (c *config) addRouteFields(ctx context.Context, r *http.Request) {
+	route := r.Pattern
+	if route == "" && c.routeFunc != nil {
+		route = c.routeFunc(r)
+	}
+	if route != "" {
+		AddFields(ctx, "route", route)
+	}
+
+	names := c.pathParams
+	if c.allPathParams {
+		names = patternWildcards(r.Pattern)
+	}
+	params := make(map[string]string, len(names))
+	for _, name := range names {
+		if v := r.PathValue(name); v != "" {
+			params[name] = v
+		}
+	}
+	if len(params) > 0 {
+		AddFields(ctx, "path_params", params)
+	}
+}
+
+// patternWildcards returns the wildcard names of a ServeMux pattern, e.g.
+// "id" and "path" for "GET /users/{id}/files/{path...}".
+func patternWildcards(pattern string) []string {
+	var names []string
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			return names
+		}
+		end := strings.IndexByte(pattern[start:], '}')
+		if end < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(pattern[start+1:start+end], "...")
+		if name != "$" && name != "" {
+			names = append(names, name)
+		}
+		pattern = pattern[start+end+1:]
+	}
+}
@@ -0,0 +1,96 @@
+package widelogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMiddleware_RouteAndPathParams(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/{org}/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /static/", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		target     string
+		wantRoute  any
+		wantParams any
+	}{
+		{"route only", nil, "/orgs/acme/users/42", "GET /orgs/{org}/users/{id}", nil},
+		{"selected params", []Option{WithPathParams("id")}, "/orgs/acme/users/42", "GET /orgs/{org}/users/{id}", map[string]any{"id": "42"}},
+		{"all params", []Option{WithPathParams()}, "/orgs/acme/users/42", "GET /orgs/{org}/users/{id}", map[string]any{"org": "acme", "id": "42"}},
+		{"no wildcards", []Option{WithPathParams()}, "/static/app.js", "GET /static/", nil},
+		{"unmatched", nil, "/missing", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+			opts := append([]Option{WithLogger(logger)}, tt.opts...)
+			Middleware(mux, opts...).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.target, nil))
+
+			var result map[string]any
+			if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+				t.Fatalf("Failed to parse log output: %v", err)
+			}
+			if result["route"] != tt.wantRoute {
+				t.Errorf("Expected route %v, got %v", tt.wantRoute, result["route"])
+			}
+			if tt.wantParams == nil {
+				if params, ok := result["path_params"]; ok {
+					t.Errorf("Expected no path_params, got %v", params)
+				}
+			} else if !reflect.DeepEqual(result["path_params"], tt.wantParams) {
+				t.Errorf("Expected path_params %v, got %v", tt.wantParams, result["path_params"])
+			}
+		})
+	}
+}
+
+func TestMiddleware_RouteFunc(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	routeFunc := func(r *http.Request) string { return "/users/:id" }
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithLogger(logger), WithRouteFunc(routeFunc),
+		WithSampler(RuleSampler{Rules: []SamplingRule{{Name: "users", Pattern: "/users/:id", Rate: 1}}}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if result["route"] != "/users/:id" {
+		t.Errorf("Expected route from RouteFunc, got %v", result["route"])
+	}
+	if result["sample_rule"] != "users" {
+		t.Errorf("Expected rule to match the route from RouteFunc, got %v", result["sample_rule"])
+	}
+}
+
+func TestPatternWildcards(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"GET /users/{id}", []string{"id"}},
+		{"/files/{dir}/{path...}", []string{"dir", "path"}},
+		{"example.com/{$}", nil},
+		{"/static/", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := patternWildcards(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("patternWildcards(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package widelogger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	mathrand "math/rand/v2"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Decision is the outcome of a Sampler.
+type Decision struct {
+	// Keep reports whether the event is emitted.
+	Keep bool
+	// Rate is the probability with which events like this one are kept.
+	// A kept event with a zero Rate is recorded with rate 1.
+	Rate float64
+	// Rule names the sampling rule that set Rate, recorded as sample_rule.
+	Rule string
+	// KeepRule names the rule that kept the event regardless of sampling,
+	// recorded as sample_keep_rule.
+	KeepRule string
+}
+
+// setSampleRate records the probability an event was kept with as sample_rate,
+// and its inverse as sample_weight, so that aggregations can sum sample_weight
+// to estimate the true number of events.
+func setSampleRate(fields map[string]any, rate float64) {
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	fields["sample_rate"] = rate
+	fields["sample_weight"] = 1 / rate
+}
+
+// setSampleRules records the rules named by d on the event.
+func setSampleRules(fields map[string]any, d Decision) {
+	if d.Rule != "" {
+		fields["sample_rule"] = d.Rule
+	}
+	if d.KeepRule != "" {
+		fields["sample_keep_rule"] = d.KeepRule
+	}
+}
+
+// Sampler decides whether an event below LevelWarn is emitted. It is given the
+// event's level, message and a snapshot of the accumulated fields together with
+// the fields of the logging call. Events at LevelWarn and above bypass it.
+// When a Sampler applies, every emitted event records the rate as sample_rate,
+// which is 1 for events that bypass sampling.
+type Sampler interface {
+	Sample(ctx context.Context, level slog.Level, msg string, snap Snapshot) Decision
+}
+
+// SamplerFunc adapts a function to the Sampler interface.
+type SamplerFunc func(ctx context.Context, level slog.Level, msg string, snap Snapshot) Decision
+
+func (f SamplerFunc) Sample(ctx context.Context, level slog.Level, msg string, snap Snapshot) Decision {
+	return f(ctx, level, msg, snap)
+}
+
+// SetSampler sets the Sampler for events below LevelWarn emitted through l,
+// including those logged by Middleware without a sampler of its own.
+// A nil s keeps every event. It is safe to call concurrently with logging.
+func (l *Logger) SetSampler(s Sampler) {
+	if s == nil {
+		l.pipeline.sampler.Store(nil)
+		return
+	}
+	l.pipeline.sampler.Store(&s)
+}
+
+func (l *Logger) loadSampler() Sampler {
+	if s := l.pipeline.sampler.Load(); s != nil {
+		return *s
+	}
+	return nil
+}
+
+// sample decides whether an event is kept. ForceLog and Suppress take
+// precedence over sampler for events below LevelWarn; other events are kept.
+func sample(ctx context.Context, level slog.Level, msg string, sampler Sampler, additionalFields []any) Decision {
+	if level >= slog.LevelWarn {
+		return Decision{Keep: true, Rate: 1}
+	}
+
+	forced, suppressed, _ := logFlags(ctx)
+	switch {
+	case forced:
+		return Decision{Keep: true, Rate: 1, KeepRule: "force_log"}
+	case suppressed:
+		return Decision{}
+	case sampler == nil:
+		return Decision{Keep: true, Rate: 1}
+	}
+
+	return sampler.Sample(ctx, level, msg, snapshotWith(ctx, additionalFields))
+}
+
+// snapshotWith returns the snapshot of ctx with the fields of a logging call merged in.
+func snapshotWith(ctx context.Context, additionalFields []any) Snapshot {
+	snap := GetSnapshot(ctx)
+	if len(additionalFields) > 0 {
+		if snap.fields == nil {
+			snap.fields = make(map[string]any, len(additionalFields)/2)
+		}
+		mergeFields(snap.fields, additionalFields)
+	}
+	return snap
+}
+
+func clampRate(rate float64) float64 {
+	if rate < 0 {
+		return 0
+	} else if rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+// RateSampler keeps each event independently with probability Rate.
+type RateSampler struct {
+	Rate float64
+}
+
+func (s RateSampler) Sample(context.Context, slog.Level, string, Snapshot) Decision {
+	rate := clampRate(s.Rate)
+	return Decision{Keep: keepRandom(rate), Rate: rate}
+}
+
+func keepRandom(rate float64) bool {
+	return rate >= 1 || mathrand.Float64() < rate
+}
+
+// HashSampler keeps an event when the hash of its key falls below Rate, so
+// every service that uses the same rate and key keeps or drops the same request.
+//
+// The hash is the first 8 bytes of the key's SHA-256 digest read as a
+// big-endian uint64, and the event is kept when hash / 2^64 < Rate.
+// Events without a key fall back to a random decision.
+type HashSampler struct {
+	Rate float64
+	// Key returns the value to hash. Defaults to DefaultSampleKey.
+	Key func(ctx context.Context, snap Snapshot) string
+}
+
+func (s HashSampler) Sample(ctx context.Context, _ slog.Level, _ string, snap Snapshot) Decision {
+	rate := clampRate(s.Rate)
+	keyFn := s.Key
+	if keyFn == nil {
+		keyFn = DefaultSampleKey
+	}
+
+	key := keyFn(ctx, snap)
+	if key == "" {
+		return Decision{Keep: keepRandom(rate), Rate: rate}
+	}
+	return Decision{Keep: hashBelow(key, rate), Rate: rate}
+}
+
+func hashBelow(key string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	sum := sha256.Sum256([]byte(key))
+	return float64(binary.BigEndian.Uint64(sum[:8])) < rate*math.Exp2(64)
+}
+
+// DefaultSampleKey returns the trace ID of ctx, so that all services of a
+// trace sample alike, falling back to the request ID and then to the
+// trace_id or request_id field of the snapshot.
+func DefaultSampleKey(ctx context.Context, snap Snapshot) string {
+	if id := GetTraceID(ctx); id != "" {
+		return id
+	}
+	if id := GetRequestID(ctx); id != "" {
+		return id
+	}
+	if v, ok := snap.Field("trace_id"); ok {
+		return fmt.Sprint(v)
+	}
+	if v, ok := snap.Field("request_id"); ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// SamplingRule matches requests for a RuleSampler. Empty fields match anything.
+type SamplingRule struct {
+	// Name is recorded in the sample_rule field when the rule matches.
+	Name string
+	// Methods lists the HTTP methods the rule applies to.
+	Methods []string
+	// Path is a path.Match pattern for the request path, e.g. "/api/search" or "/static/*".
+	Path string
+	// Pattern matches the ServeMux pattern of the request exactly, e.g. "GET /users/{id}".
+	Pattern string
+	// Host is a path.Match pattern for the request host, e.g. "*.example.com".
+	Host string
+	// Rate is the probability with which matching requests are kept.
+	Rate float64
+}
+
+// RuleSampler applies the rate of the first matching rule, or DefaultRate if no
+// rule matches. The matched rule's name, or "default", is recorded on the event
+// as sample_rule.
+//
+// Rules match against the request seen by the handler, so Pattern is available
+// once a Go 1.22+ ServeMux has routed it, or from the route field recorded with
+// WithRouteFunc. Outside Middleware, rules match the method, path, host and
+// route fields of the snapshot.
+type RuleSampler struct {
+	Rules       []SamplingRule
+	DefaultRate float64
+}
+
+func (s RuleSampler) Sample(ctx context.Context, _ slog.Level, _ string, snap Snapshot) Decision {
+	info := requestInfoFrom(ctx, snap)
+
+	name, rate := "default", s.DefaultRate
+	for i, rule := range s.Rules {
+		if rule.matches(info) {
+			name, rate = rule.Name, rule.Rate
+			if name == "" {
+				name = fmt.Sprintf("rule_%d", i)
+			}
+			break
+		}
+	}
+
+	rate = clampRate(rate)
+	return Decision{Keep: keepRandom(rate), Rate: rate, Rule: name}
+}
+
+type requestInfo struct {
+	method, path, pattern, host string
+}
+
+func requestInfoFrom(ctx context.Context, snap Snapshot) requestInfo {
+	field := func(key string) string {
+		v, _ := snap.Field(key)
+		s, _ := v.(string)
+		return s
+	}
+	if r := requestFromContext(ctx); r != nil {
+		info := requestInfo{method: r.Method, path: r.URL.Path, pattern: r.Pattern, host: r.Host}
+		if info.pattern == "" {
+			info.pattern = field("route")
+		}
+		return info
+	}
+	return requestInfo{method: field("method"), path: field("path"), pattern: field("route"), host: field("host")}
+}
+
+func (rule SamplingRule) matches(info requestInfo) bool {
+	if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool {
+		return strings.EqualFold(m, info.method)
+	}) {
+		return false
+	}
+	if rule.Pattern != "" && rule.Pattern != info.pattern {
+		return false
+	}
+	if rule.Path != "" {
+		if ok, _ := path.Match(rule.Path, info.path); !ok {
+			return false
+		}
+	}
+	if rule.Host != "" {
+		if ok, _ := path.Match(rule.Host, info.host); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// KeepRule keeps an event regardless of the base sampler. All conditions set
+// on a rule must hold; a rule with no conditions never matches.
+type KeepRule struct {
+	// Name is recorded in the sample_keep_rule field when the rule keeps an event.
+	Name string
+	// MinDuration matches events whose duration_ms field is at least this long.
+	MinDuration time.Duration
+	// Field and Equals match events whose field equals the value, compared by
+	// their fmt.Sprint representation.
+	Field  string
+	Equals any
+	// WarningCode matches events with a warning carrying this "code" field,
+	// e.g. AddWarning(ctx, "stale cache", "code", "STALE_CACHE").
+	WarningCode string
+	// Predicate matches events for which it returns true.
+	Predicate func(ctx context.Context, snap Snapshot) bool
+}
+
+// TailSampler evaluates keep rules against the fully accumulated event and
+// keeps every match; other events are left to Base. A nil Base keeps them all.
+type TailSampler struct {
+	Rules []KeepRule
+	Base  Sampler
+}
+
+func (s TailSampler) Sample(ctx context.Context, level slog.Level, msg string, snap Snapshot) Decision {
+	for i, rule := range s.Rules {
+		if rule.matches(ctx, snap) {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("keep_rule_%d", i)
+			}
+			return Decision{Keep: true, Rate: 1, KeepRule: name}
+		}
+	}
+	if s.Base == nil {
+		return Decision{Keep: true, Rate: 1}
+	}
+	return s.Base.Sample(ctx, level, msg, snap)
+}
+
+func (rule KeepRule) matches(ctx context.Context, snap Snapshot) bool {
+	if rule.MinDuration <= 0 && rule.Field == "" && rule.WarningCode == "" && rule.Predicate == nil {
+		return false
+	}
+	if rule.MinDuration > 0 {
+		v, _ := snap.Field("duration_ms")
+		ms, ok := toFloat(v)
+		if !ok || time.Duration(ms*float64(time.Millisecond)) < rule.MinDuration {
+			return false
+		}
+	}
+	if rule.Field != "" {
+		v, ok := snap.Field(rule.Field)
+		if !ok || fmt.Sprint(v) != fmt.Sprint(rule.Equals) {
+			return false
+		}
+	}
+	if rule.WarningCode != "" && !slices.ContainsFunc(snap.warnings, func(w Warning) bool {
+		return fmt.Sprint(w.Fields["code"]) == rule.WarningCode
+	}) {
+		return false
+	}
+	if rule.Predicate != nil && !rule.Predicate(ctx, snap) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,419 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateSampler(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		wantKeep bool
+		wantRate float64
+	}{
+		{0, false, 0},
+		{-1, false, 0},
+		{1, true, 1},
+		{2, true, 1},
+	}
+
+	for _, tt := range tests {
+		d := RateSampler{Rate: tt.rate}.Sample(context.Background(), slog.LevelInfo, "", Snapshot{})
+		if d.Keep != tt.wantKeep || d.Rate != tt.wantRate {
+			t.Errorf("RateSampler{%v} = %+v, want keep=%v rate=%v", tt.rate, d, tt.wantKeep, tt.wantRate)
+		}
+	}
+}
+
+func TestHashSampler_Consistent(t *testing.T) {
+	// two services configured independently with the same rate
+	serviceA := HashSampler{Rate: 0.3}
+	serviceB := HashSampler{Rate: 0.3}
+
+	kept := 0
+	for i := 0; i < 2000; i++ {
+		ctx := NewContext(context.Background())
+		AddFields(ctx, "request_id", fmt.Sprintf("req-%d", i))
+		snap := GetSnapshot(ctx)
+
+		a := serviceA.Sample(ctx, slog.LevelInfo, "", snap)
+		b := serviceB.Sample(ctx, slog.LevelInfo, "", snap)
+		if a.Keep != b.Keep {
+			t.Fatalf("req-%d: services disagree (%v vs %v)", i, a.Keep, b.Keep)
+		}
+		if a.Rate != 0.3 {
+			t.Fatalf("Expected rate 0.3, got %v", a.Rate)
+		}
+		if a.Keep {
+			kept++
+		}
+	}
+
+	if kept < 500 || kept > 700 {
+		t.Errorf("Expected roughly 30%% of 2000 requests kept, got %d", kept)
+	}
+}
+
+func TestHashSampler_CustomKey(t *testing.T) {
+	s := HashSampler{
+		Rate: 0.5,
+		Key: func(ctx context.Context, snap Snapshot) string {
+			v, _ := snap.Field("tenant")
+			return fmt.Sprint(v)
+		},
+	}
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "tenant", "acme")
+	first := s.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx)).Keep
+	for i := 0; i < 20; i++ {
+		if got := s.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx)).Keep; got != first {
+			t.Fatal("Expected the same decision for the same key")
+		}
+	}
+}
+
+func TestMiddleware_WithSampler(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	sampler := HashSampler{Rate: 0.5}
+
+	serviceA := Middleware(ok, WithLogger(New(slog.New(slog.NewJSONHandler(&bufA, nil)))), WithRequestID(), WithSampler(sampler))
+	serviceB := Middleware(ok, WithLogger(New(slog.New(slog.NewJSONHandler(&bufB, nil)))), WithRequestID(), WithSampler(sampler))
+
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		for _, svc := range []http.Handler{serviceA, serviceB} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Request-ID", id)
+			svc.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	if n := bytes.Count(bufA.Bytes(), []byte("\n")); n == 0 || n == 50 {
+		t.Fatalf("Expected some but not all requests to be kept, got %d", n)
+	}
+	for i := 0; i < 50; i++ {
+		id := []byte(fmt.Sprintf(`"trace-%d"`, i))
+		if bytes.Contains(bufA.Bytes(), id) != bytes.Contains(bufB.Bytes(), id) {
+			t.Errorf("trace-%d: services made different decisions", i)
+		}
+	}
+
+	// errors bypass the sampler
+	bufA.Reset()
+	drop := SamplerFunc(func(context.Context, slog.Level, string, Snapshot) Decision { return Decision{} })
+	failing := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}), WithLogger(New(slog.New(slog.NewJSONHandler(&bufA, nil)))), WithSampler(drop))
+	failing.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if bufA.Len() == 0 {
+		t.Error("Expected 500 response to bypass the sampler")
+	}
+}
+
+func TestRuleSampler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	sampler := RuleSampler{
+		Rules: []SamplingRule{
+			{Name: "search", Path: "/api/search", Rate: 0},
+			{Name: "checkout", Methods: []string{"POST"}, Pattern: "POST /checkout/{id}", Rate: 1},
+			{Name: "internal", Host: "*.internal", Rate: 1},
+		},
+		DefaultRate: 0,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /checkout/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Middleware(mux, WithLogger(logger), WithSampler(sampler))
+
+	tests := []struct {
+		method, target, host string
+		wantRule             string
+	}{
+		{"GET", "/api/search", "", ""},
+		{"POST", "/checkout/42", "", "checkout"},
+		{"GET", "/other", "billing.internal", "internal"},
+		{"GET", "/other", "", ""},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if tt.wantRule == "" {
+			if buf.Len() > 0 {
+				t.Errorf("%s %s: expected event to be dropped, got %q", tt.method, tt.target, buf.String())
+			}
+			continue
+		}
+		want := fmt.Sprintf(`"sample_rule":%q`, tt.wantRule)
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("%s %s: expected %s, got %q", tt.method, tt.target, want, buf.String())
+		}
+	}
+}
+
+func TestRuleSampler_Snapshot(t *testing.T) {
+	sampler := RuleSampler{
+		Rules:       []SamplingRule{{Methods: []string{"get"}, Path: "/jobs/*", Rate: 1}},
+		DefaultRate: 0,
+	}
+
+	ctx := NewContext(context.Background())
+	AddFields(ctx, "method", "GET", "path", "/jobs/reindex")
+	d := sampler.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx))
+	if !d.Keep || d.Rate != 1 {
+		t.Errorf("Expected match on snapshot fields, got %+v", d)
+	}
+	if d.Rule != "rule_0" {
+		t.Errorf("Expected unnamed rule to be reported as rule_0, got %q", d.Rule)
+	}
+
+	ctx = NewContext(context.Background())
+	AddFields(ctx, "method", "GET", "path", "/other")
+	d = sampler.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx))
+	if d.Keep {
+		t.Errorf("Expected default rate to apply, got %+v", d)
+	}
+	if d.Rule != "default" {
+		t.Errorf("Expected rule default, got %q", d.Rule)
+	}
+	if _, ok := GetSnapshot(ctx).Field("sample_rule"); ok {
+		t.Error("Expected the sampler not to modify the context")
+	}
+}
+
+func TestTailSampler(t *testing.T) {
+	dropAll := RateSampler{Rate: 0}
+	sampler := TailSampler{
+		Rules: []KeepRule{
+			{Name: "slow", MinDuration: 500 * time.Millisecond},
+			{Name: "enterprise", Field: "plan", Equals: "enterprise"},
+			{Name: "stale_cache", WarningCode: "STALE_CACHE"},
+			{Predicate: func(ctx context.Context, snap Snapshot) bool {
+				v, _ := snap.Field("retries")
+				return v == 3
+			}},
+			{Name: "empty"},
+		},
+		Base: dropAll,
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(ctx context.Context)
+		wantRule string
+	}{
+		{"slow request", func(ctx context.Context) { AddFields(ctx, "duration_ms", int64(750)) }, "slow"},
+		{"fast request", func(ctx context.Context) { AddFields(ctx, "duration_ms", int64(20)) }, ""},
+		{"field match", func(ctx context.Context) { AddFields(ctx, "plan", "enterprise") }, "enterprise"},
+		{"field mismatch", func(ctx context.Context) { AddFields(ctx, "plan", "free") }, ""},
+		{"warning code", func(ctx context.Context) { AddWarning(ctx, "stale cache", "code", "STALE_CACHE") }, "stale_cache"},
+		{"predicate", func(ctx context.Context) { AddFields(ctx, "retries", 3) }, "keep_rule_3"},
+		{"no match", func(ctx context.Context) {}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background())
+			tt.setup(ctx)
+			d := sampler.Sample(ctx, slog.LevelInfo, "", GetSnapshot(ctx))
+
+			rule := d.KeepRule
+			if tt.wantRule == "" {
+				if d.Keep || rule != "" {
+					t.Errorf("Expected fallback to the base sampler, got %+v rule=%v", d, rule)
+				}
+				return
+			}
+			if !d.Keep || d.Rate != 1 || rule != tt.wantRule {
+				t.Errorf("Expected keep by %s, got %+v rule=%v", tt.wantRule, d, rule)
+			}
+		})
+	}
+}
+
+func TestMiddleware_TailSampler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddFields(r.Context(), "plan", r.URL.Query().Get("plan"))
+	}), WithLogger(New(slog.New(slog.NewJSONHandler(&buf, nil)))), WithSampler(TailSampler{
+		Rules: []KeepRule{{Name: "enterprise", Field: "plan", Equals: "enterprise"}},
+		Base:  RateSampler{Rate: 0},
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?plan=free", nil))
+	if buf.Len() > 0 {
+		t.Fatalf("Expected free plan request to be sampled out, got %q", buf.String())
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?plan=enterprise", nil))
+	if !bytes.Contains(buf.Bytes(), []byte(`"sample_keep_rule":"enterprise"`)) {
+		t.Errorf("Expected enterprise request to be kept with its rule, got %q", buf.String())
+	}
+}
+
+func TestMiddleware_SampleRate(t *testing.T) {
+	keepHalf := SamplerFunc(func(context.Context, slog.Level, string, Snapshot) Decision {
+		return Decision{Keep: true, Rate: 0.25}
+	})
+	keepUnknown := SamplerFunc(func(context.Context, slog.Level, string, Snapshot) Decision {
+		return Decision{Keep: true}
+	})
+
+	tests := []struct {
+		name       string
+		status     int
+		opts       []Option
+		wantRate   float64
+		wantWeight float64
+	}{
+		{"no sampler", http.StatusOK, nil, 1, 1},
+		{"sampled", http.StatusOK, []Option{WithSampler(keepHalf)}, 0.25, 4},
+		{"error bypasses sampler", http.StatusInternalServerError, []Option{WithSampler(keepHalf)}, 1, 1},
+		{"zero rate", http.StatusOK, []Option{WithSampler(keepUnknown)}, 1, 1},
+		{"success sampling", http.StatusOK, []Option{WithSuccessSampling(1)}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]Option{WithLogger(New(slog.New(slog.NewJSONHandler(&buf, nil))))}, tt.opts...)
+			Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}), opts...).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			var result map[string]any
+			if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+				t.Fatalf("Failed to parse log output: %v", err)
+			}
+			if result["sample_rate"] != tt.wantRate || result["sample_weight"] != tt.wantWeight {
+				t.Errorf("Expected sample_rate=%v sample_weight=%v, got %v and %v",
+					tt.wantRate, tt.wantWeight, result["sample_rate"], result["sample_weight"])
+			}
+		})
+	}
+}
+
+func TestLogger_SetSampler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.SetSampler(SamplerFunc(func(_ context.Context, _ slog.Level, _ string, snap Snapshot) Decision {
+		v, _ := snap.Field("job")
+		return Decision{Keep: v == "keep", Rate: 0.5}
+	}))
+
+	ctx := NewContext(context.Background())
+	logger.Info(ctx, "job_done", "job", "drop")
+	logger.Info(ctx, "job_done", "job", "keep")
+	logger.Warn(ctx, "job_slow", "job", "drop")
+
+	var results []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var result map[string]any
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(results))
+	}
+	if results[0]["job"] != "keep" || results[0]["sample_rate"] != 0.5 || results[0]["sample_weight"] != 2.0 {
+		t.Errorf("Expected sampled info event with rate 0.5, got %v", results[0])
+	}
+	if results[1]["msg"] != "job_slow" || results[1]["sample_rate"] != 1.0 {
+		t.Errorf("Expected warning to bypass the sampler with rate 1, got %v", results[1])
+	}
+
+	buf.Reset()
+	logger.SetSampler(nil)
+	logger.Info(ctx, "job_done", "job", "drop")
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if _, ok := result["sample_rate"]; ok {
+		t.Errorf("Expected no sample_rate without a sampler, got %v", result["sample_rate"])
+	}
+}
+
+func TestMiddleware_LoggerSampler(t *testing.T) {
+	dropAll := RateSampler{Rate: 0}
+	keepAll := RateSampler{Rate: 1}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantLogs bool
+	}{
+		{"logger sampler", nil, false},
+		{"middleware sampler overrides", []Option{WithSampler(keepAll)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+			logger.SetSampler(dropAll)
+
+			opts := append([]Option{WithLogger(logger)}, tt.opts...)
+			Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), opts...).
+				ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			if got := buf.Len() > 0; got != tt.wantLogs {
+				t.Errorf("Expected logged=%v, got output %q", tt.wantLogs, buf.String())
+			}
+		})
+	}
+}
+
+func TestLogger_SamplerRulesPerEvent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.SetSampler(RuleSampler{DefaultRate: 1})
+
+	// a context not created by NewContext, as in a job worker
+	ctx := context.Background()
+	logger.Info(ctx, "job_done")
+	logger.SetSampler(nil)
+	logger.Info(ctx, "job_done")
+
+	var results []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var result map[string]any
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 events, got %d: %v", len(results), results)
+	}
+	if results[0]["sample_rule"] != "default" {
+		t.Errorf("Expected sample_rule on the sampled event, got %v", results[0]["sample_rule"])
+	}
+	if _, ok := results[1]["sample_rule"]; ok {
+		t.Errorf("Expected no sample_rule once the sampler is removed, got %v", results[1]["sample_rule"])
+	}
+}
@@ -0,0 +1,210 @@
+package widelogger
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// summaryReservoirSize is the number of durations kept per key for percentiles.
+const summaryReservoirSize = 1024
+
+// SamplingSummaryOptions configures StartSamplingSummary.
+type SamplingSummaryOptions struct {
+	// Interval is how often the summary is emitted. Defaults to one minute.
+	Interval time.Duration
+	// MaxKeys bounds the number of keys counted per interval. Defaults to 1000;
+	// events of further keys only count towards dropped_total.
+	MaxKeys int
+}
+
+// summaryKey groups dropped events in a sampling summary.
+type summaryKey struct {
+	route  string
+	status int
+	level  slog.Level
+}
+
+type summaryEntry struct {
+	count     int
+	durations []float64
+}
+
+// samplingSummary counts events dropped by sampling between two summaries.
+type samplingSummary struct {
+	maxKeys int
+
+	mu      sync.Mutex
+	start   time.Time
+	total   int
+	entries map[summaryKey]*summaryEntry
+}
+
+// StartSamplingSummary makes l count the events it drops through sampling or
+// Suppress, grouped by route, status code and level, and emit them every
+// interval as a widelogger_sampling_summary event. For each group the event
+// holds the number of dropped events and, for requests, percentiles of their
+// duration_ms, so that volume and latency stay known at low sampling rates.
+// Intervals without dropped events are not reported.
+//
+// The returned function stops the summary and emits what was counted since the
+// last one. Starting a new summary replaces the previous one.
+func (l *Logger) StartSamplingSummary(opts *SamplingSummaryOptions) (stop func()) {
+	var o SamplingSummaryOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Minute
+	}
+	if o.MaxKeys <= 0 {
+		o.MaxKeys = 1000
+	}
+
+	s := &samplingSummary{
+		maxKeys: o.MaxKeys,
+		start:   time.Now(),
+		entries: make(map[summaryKey]*summaryEntry),
+	}
+	l.pipeline.summary.Store(s)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(o.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.emitSummary(s)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// detach first so events dropped from now on are not counted
+			// into a summary that is no longer flushed
+			l.pipeline.summary.CompareAndSwap(s, nil)
+			close(done)
+			<-stopped
+			l.emitSummary(s)
+		})
+	}
+}
+
+func (l *Logger) emitSummary(s *samplingSummary) {
+	if attrs := s.flush(time.Now()); attrs != nil {
+		l.logger.LogAttrs(context.Background(), slog.LevelInfo, "widelogger_sampling_summary", attrs...)
+	}
+}
+
+// record counts a dropped event.
+func (s *samplingSummary) record(ctx context.Context, level slog.Level, snap Snapshot) {
+	info := requestInfoFrom(ctx, snap)
+	key := summaryKey{route: info.pattern, level: level}
+	if key.route == "" && info.path != "" {
+		key.route = info.method + " " + info.path
+	}
+	if v, ok := snap.Field("status_code"); ok {
+		key.status, _ = v.(int)
+	}
+	v, _ := snap.Field("duration_ms")
+	duration, hasDuration := toFloat(v)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.total++
+	entry, ok := s.entries[key]
+	if !ok {
+		if len(s.entries) >= s.maxKeys {
+			return
+		}
+		entry = &summaryEntry{}
+		s.entries[key] = entry
+	}
+	entry.count++
+	if !hasDuration {
+		return
+	}
+	// reservoir sampling keeps a uniform sample of the key's durations
+	if len(entry.durations) < summaryReservoirSize {
+		entry.durations = append(entry.durations, duration)
+	} else if i := mathrand.IntN(entry.count); i < summaryReservoirSize {
+		entry.durations[i] = duration
+	}
+}
+
+// flush returns the summary attributes counted since the last flush and resets
+// the counts. It returns nil if no events were dropped.
+func (s *samplingSummary) flush(now time.Time) []slog.Attr {
+	s.mu.Lock()
+	start, total, entries := s.start, s.total, s.entries
+	s.start, s.total, s.entries = now, 0, make(map[summaryKey]*summaryEntry, len(entries))
+	s.mu.Unlock()
+
+	if total == 0 {
+		return nil
+	}
+
+	keys := make([]summaryKey, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b summaryKey) int {
+		if c := cmp.Compare(entries[b].count, entries[a].count); c != 0 {
+			return c
+		}
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.status, b.status), cmp.Compare(a.level, b.level))
+	})
+
+	dropped := make([]map[string]any, len(keys))
+	for i, k := range keys {
+		entry := entries[k]
+		group := map[string]any{
+			"level": k.level.String(),
+			"count": entry.count,
+		}
+		if k.route != "" {
+			group["route"] = k.route
+		}
+		if k.status != 0 {
+			group["status_code"] = k.status
+		}
+		if len(entry.durations) > 0 {
+			group["duration_ms"] = percentiles(entry.durations)
+		}
+		dropped[i] = group
+	}
+
+	return []slog.Attr{
+		slog.Float64("interval_ms", durationMS(now.Sub(start))),
+		slog.Int("dropped_total", total),
+		slog.Any("dropped", dropped),
+	}
+}
+
+// percentiles returns the nearest-rank p50, p90 and p99 and the maximum of durations.
+func percentiles(durations []float64) map[string]float64 {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	rank := func(p int) float64 {
+		i := (p*len(sorted)+99)/100 - 1
+		return sorted[max(i, 0)]
+	}
+	return map[string]float64{
+		"p50": rank(50),
+		"p90": rank(90),
+		"p99": rank(99),
+		"max": sorted[len(sorted)-1],
+	}
+}
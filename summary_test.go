@@ -0,0 +1,135 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSamplingSummary(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.SetSampler(RateSampler{Rate: 0})
+	stop := logger.StartSamplingSummary(&SamplingSummaryOptions{Interval: time.Hour})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Middleware(mux, WithLogger(logger))
+	for range 10 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+	}
+	logger.Debug(NewContext(context.Background()), "cache_refreshed")
+	logger.Warn(NewContext(context.Background()), "cache_stale")
+
+	if bytes.Contains(buf.Bytes(), []byte("widelogger_sampling_summary")) {
+		t.Fatal("Expected no summary before the interval or stop")
+	}
+	buf.Reset()
+	stop()
+
+	var result struct {
+		Msg          string           `json:"msg"`
+		DroppedTotal int              `json:"dropped_total"`
+		Dropped      []map[string]any `json:"dropped"`
+	}
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+
+	if result.Msg != "widelogger_sampling_summary" {
+		t.Errorf("Expected summary event, got %q", result.Msg)
+	}
+	if result.DroppedTotal != 11 {
+		t.Errorf("Expected 11 dropped events, got %d", result.DroppedTotal)
+	}
+	if len(result.Dropped) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", result.Dropped)
+	}
+
+	route := result.Dropped[0]
+	if route["route"] != "GET /users/{id}" || route["status_code"] != 200.0 || route["level"] != "INFO" || route["count"] != 10.0 {
+		t.Errorf("Unexpected route group: %v", route)
+	}
+	durations, ok := route["duration_ms"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected duration percentiles, got %v", route["duration_ms"])
+	}
+	for _, p := range []string{"p50", "p90", "p99", "max"} {
+		if _, ok := durations[p]; !ok {
+			t.Errorf("Expected %s in duration percentiles, got %v", p, durations)
+		}
+	}
+
+	other := result.Dropped[1]
+	if other["level"] != "DEBUG" || other["count"] != 1.0 || other["route"] != nil {
+		t.Errorf("Unexpected non-request group: %v", other)
+	}
+
+	buf.Reset()
+	stop()
+	logger.Info(NewContext(context.Background()), "after_stop")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output after stop, got %q", buf.String())
+	}
+}
+
+func TestSamplingSummary_Periodic(t *testing.T) {
+	var buf syncBuffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.SetSampler(RateSampler{Rate: 0})
+	stop := logger.StartSamplingSummary(&SamplingSummaryOptions{Interval: 10 * time.Millisecond})
+	defer stop()
+
+	logger.Info(NewContext(context.Background()), "dropped")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !bytes.Contains(buf.Bytes(), []byte("widelogger_sampling_summary")) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a summary within the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	durations := make([]float64, 100)
+	for i := range durations {
+		durations[i] = float64(100 - i)
+	}
+
+	got := percentiles(durations)
+	want := map[string]float64{"p50": 50, "p90": 90, "p99": 99, "max": 100}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, got[k])
+		}
+	}
+
+	if got := percentiles([]float64{7}); got["p50"] != 7 || got["p99"] != 7 {
+		t.Errorf("Expected single duration for every percentile, got %v", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for a background writer.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
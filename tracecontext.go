@@ -0,0 +1,140 @@
+package widelogger
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+type traceContextKey struct{}
+
+// spanContext is the W3C Trace Context of a request as handled by this service.
+type spanContext struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	flags        byte
+}
+
+func (sc spanContext) sampled() bool {
+	return sc.flags&0x01 != 0
+}
+
+func (sc spanContext) traceparent() string {
+	return "00-" + sc.traceID + "-" + sc.spanID + "-" + hex.EncodeToString([]byte{sc.flags})
+}
+
+// TraceContextConfig configures W3C Trace Context handling.
+type TraceContextConfig struct {
+	// PropagateToResponse sets the traceparent header of the response to the
+	// request's trace ID and span ID.
+	PropagateToResponse bool
+}
+
+// WithTraceContext makes the middleware continue the trace of an inbound W3C
+// traceparent header, or start a new sampled trace when it is missing or
+// invalid. Each request gets a new span ID; the trace_id, span_id and
+// trace_sampled fields are recorded, and for a continued trace also
+// parent_span_id and the inbound tracestate. Use GetTraceID and GetSpanID to
+// read them in handlers.
+func WithTraceContext(cfg ...*TraceContextConfig) Option {
+	return func(c *config) {
+		res := &TraceContextConfig{}
+		if len(cfg) > 0 && cfg[0] != nil {
+			*res = *cfg[0]
+		}
+		c.traceContextConfig = res
+	}
+}
+
+// startSpan returns the span context of a request carrying header h, and
+// whether it continues the inbound trace rather than starting a new one.
+func startSpan(h http.Header) (spanContext, bool) {
+	sc, ok := parseTraceparent(h.Get(traceparentHeader))
+	if !ok {
+		return spanContext{traceID: randomHex(16), spanID: randomHex(8), flags: 0x01}, false
+	}
+	sc.parentSpanID, sc.spanID = sc.spanID, randomHex(8)
+	return sc, true
+}
+
+// parseTraceparent parses a traceparent header value as specified by
+// https://www.w3.org/TR/trace-context/#traceparent-header. Values of future
+// versions are accepted if they start with a valid version 00 value.
+func parseTraceparent(v string) (spanContext, bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return spanContext{}, false
+	}
+	version := v[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return spanContext{}, false
+	}
+	if version == "00" && len(v) != 55 {
+		return spanContext{}, false
+	}
+	if len(v) > 55 && v[55] != '-' {
+		return spanContext{}, false
+	}
+
+	traceID, spanID, flags := v[3:35], v[36:52], v[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return spanContext{}, false
+	}
+	if traceID == strings.Repeat("0", 32) || spanID == strings.Repeat("0", 16) {
+		return spanContext{}, false
+	}
+
+	b, _ := hex.DecodeString(flags)
+	return spanContext{traceID: traceID, spanID: spanID, flags: b[0]}, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		_, _ = cryptorand.Read(b)
+		// all-zero IDs are invalid
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
+
+// GetTraceID returns the W3C trace ID of the request, or "" if the middleware
+// was not configured WithTraceContext.
+func GetTraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sc, _ := ctx.Value(traceContextKey{}).(spanContext)
+	return sc.traceID
+}
+
+// GetSpanID returns the span ID the middleware assigned to the request, or ""
+// if it was not configured WithTraceContext.
+func GetSpanID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sc, _ := ctx.Value(traceContextKey{}).(spanContext)
+	return sc.spanID
+}
@@ -0,0 +1,149 @@
+package widelogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"version 00 with extra", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"short", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := parseTraceparent(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.spanID != "00f067aa0ba902b7" {
+				t.Errorf("Unexpected IDs: %+v", sc)
+			}
+			if sc.sampled() != tt.sampled {
+				t.Errorf("Expected sampled=%v, got %v", tt.sampled, sc.sampled())
+			}
+		})
+	}
+}
+
+func TestMiddleware_TraceContext(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	var gotTraceID, gotSpanID string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceID, gotSpanID = GetTraceID(r.Context()), GetSpanID(r.Context())
+	}), WithLogger(logger), WithTraceContext(&TraceContextConfig{PropagateToResponse: true}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-00")
+	req.Header.Set("tracestate", "vendor=abc")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+
+	if gotTraceID != traceID || result["trace_id"] != traceID {
+		t.Errorf("Expected inbound trace ID, got %q and %v", gotTraceID, result["trace_id"])
+	}
+	if len(gotSpanID) != 16 || gotSpanID == "00f067aa0ba902b7" || result["span_id"] != gotSpanID {
+		t.Errorf("Expected a new span ID, got %q and %v", gotSpanID, result["span_id"])
+	}
+	if result["parent_span_id"] != "00f067aa0ba902b7" {
+		t.Errorf("Expected inbound span as parent, got %v", result["parent_span_id"])
+	}
+	if result["trace_sampled"] != false || result["tracestate"] != "vendor=abc" {
+		t.Errorf("Expected trace_sampled=false and tracestate, got %v and %v", result["trace_sampled"], result["tracestate"])
+	}
+	if want := "00-" + traceID + "-" + gotSpanID + "-00"; rec.Header().Get("traceparent") != want {
+		t.Errorf("Expected response traceparent %q, got %q", want, rec.Header().Get("traceparent"))
+	}
+}
+
+func TestMiddleware_TraceContext_Generated(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithLogger(logger), WithTraceContext())
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "invalid")
+	req.Header.Set("tracestate", "vendor=abc")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+
+	traceID, _ := result["trace_id"].(string)
+	if _, ok := parseTraceparent("00-" + traceID + "-00f067aa0ba902b7-01"); !ok {
+		t.Errorf("Expected a valid generated trace ID, got %v", result["trace_id"])
+	}
+	if _, ok := result["parent_span_id"]; ok {
+		t.Errorf("Expected no parent span for a new trace, got %v", result["parent_span_id"])
+	}
+	if _, ok := result["tracestate"]; ok {
+		t.Errorf("Expected tracestate to be discarded for a new trace, got %v", result["tracestate"])
+	}
+	if result["trace_sampled"] != true {
+		t.Errorf("Expected new traces to be sampled, got %v", result["trace_sampled"])
+	}
+	if rec.Header().Get("traceparent") != "" {
+		t.Error("Expected no response traceparent by default")
+	}
+}
+
+func TestGetTraceID_NoTraceContext(t *testing.T) {
+	if GetTraceID(context.Background()) != "" || GetSpanID(context.Background()) != "" {
+		t.Error("Expected empty IDs without trace context")
+	}
+}
+
+func TestDefaultSampleKey_PrefersTraceID(t *testing.T) {
+	var key string
+	sampler := SamplerFunc(func(ctx context.Context, _ slog.Level, _ string, snap Snapshot) Decision {
+		key = DefaultSampleKey(ctx, snap)
+		return Decision{Keep: true}
+	})
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithLogger(New(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))),
+		WithRequestID(), WithTraceContext(), WithSampler(sampler))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if key != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected trace ID as sample key, got %q", key)
+	}
+}
@@ -0,0 +1,87 @@
+package widelogger
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+const (
+	metricCPUUser           = "/cpu/classes/user:cpu-seconds"
+	metricHeapAllocBytes    = "/gc/heap/allocs:bytes"
+	metricHeapAllocObjects  = "/gc/heap/allocs:objects"
+	metricGoroutinesCreated = "/sched/goroutines-created:goroutines"
+)
+
+// usageSample is a reading of process-level runtime metrics taken at the
+// start of a request. Deltas against a later reading approximate what the
+// request consumed; concurrent requests inflate each other's numbers.
+type usageSample struct {
+	start      time.Time
+	goroutines int
+	samples    []metrics.Sample
+}
+
+func newUsageSample() *usageSample {
+	u := &usageSample{
+		start:      time.Now(),
+		goroutines: runtime.NumGoroutine(),
+		samples: []metrics.Sample{
+			{Name: metricCPUUser},
+			{Name: metricHeapAllocBytes},
+			{Name: metricHeapAllocObjects},
+			{Name: metricGoroutinesCreated},
+		},
+	}
+	metrics.Read(u.samples)
+	return u
+}
+
+// fields returns the usage group for the request, given the time spent in
+// ResponseWriter.Write calls.
+func (u *usageSample) fields(writeDuration time.Duration) map[string]any {
+	wall := time.Since(u.start)
+	end := make([]metrics.Sample, len(u.samples))
+	for i := range u.samples {
+		end[i].Name = u.samples[i].Name
+	}
+	metrics.Read(end)
+
+	usage := map[string]any{
+		"approximate": true,
+		"wall_ms":     durationMS(wall),
+		"handler_ms":  durationMS(wall - writeDuration),
+		"write_ms":    durationMS(writeDuration),
+	}
+
+	for i, s := range end {
+		switch s.Value.Kind() {
+		case metrics.KindFloat64:
+			delta := s.Value.Float64() - u.samples[i].Value.Float64()
+			if s.Name == metricCPUUser {
+				usage["process_cpu_ms"] = delta * 1000
+			}
+		case metrics.KindUint64:
+			delta := s.Value.Uint64() - u.samples[i].Value.Uint64()
+			switch s.Name {
+			case metricHeapAllocBytes:
+				usage["process_alloc_bytes"] = delta
+			case metricHeapAllocObjects:
+				usage["process_alloc_objects"] = delta
+			case metricGoroutinesCreated:
+				usage["goroutines_spawned"] = delta
+			}
+		}
+	}
+
+	// older runtimes do not report goroutine creation; fall back to the live count
+	if _, ok := usage["goroutines_spawned"]; !ok {
+		usage["goroutines_spawned"] = max(0, runtime.NumGoroutine()-u.goroutines)
+	}
+
+	return usage
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
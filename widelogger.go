@@ -6,16 +6,26 @@ import (
 	"log/slog"
 	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type contextKey struct{}
 
+type loggerContextKey struct{}
+
 var (
 	fieldsContextKey = contextKey{}
 	defaultLogger    = slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	defaultLoggerMu  sync.RWMutex
+
+	maxTimelineEvents atomic.Int64
 )
 
+func init() {
+	maxTimelineEvents.Store(100)
+}
+
 // SetDefaultLogger sets the global default logger used by package-level functions.
 func SetDefaultLogger(logger *slog.Logger) {
 	if logger == nil {
@@ -32,34 +42,90 @@ func getDefaultLogger() *slog.Logger {
 	return defaultLogger
 }
 
+// SetMaxTimelineEvents sets how many timeline events a context keeps.
+// Events added beyond the cap are counted in timeline_dropped. A value of
+// zero or less removes the cap. The default is 100.
+func SetMaxTimelineEvents(n int) {
+	maxTimelineEvents.Store(int64(n))
+}
+
 // Warning represents a non-fatal issue that occurred during request processing.
 type Warning struct {
 	Message string         `json:"message"`
 	Fields  map[string]any `json:"fields,omitempty"`
 }
 
+// TimelineEvent is a point-in-time event recorded with AddEvent.
+type TimelineEvent struct {
+	Name     string         `json:"name"`
+	OffsetMS float64        `json:"offset_ms"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
 type fieldContainer struct {
-	mu       sync.Mutex
-	fields   map[string]any
-	warnings []Warning
-	errors   []Warning
+	mu              sync.Mutex
+	start           time.Time
+	fields          map[string]any
+	warnings        []Warning
+	errors          []Warning
+	timeline        []TimelineEvent
+	timelineDropped int
+
+	forceLog bool
+	suppress bool
+	debug    bool
 }
 
 type Logger struct {
-	logger *slog.Logger
+	logger   *slog.Logger
+	level    *levelControl
+	pipeline *pipeline
 }
 
+// pipeline holds the processors, Sampler and sampling summary of a Logger.
+// It is shared by all Loggers derived from the same New call.
+type pipeline struct {
+	processors atomic.Pointer[[]Processor]
+	sampler    atomic.Pointer[Sampler]
+	summary    atomic.Pointer[samplingSummary]
+}
+
+// New wraps logger in a Logger. A nil logger uses the global default.
+// The minimum level starts at the lowest level the handler accepts and can be
+// changed at runtime with SetLevel.
 func New(logger *slog.Logger) *Logger {
 	if logger == nil {
 		logger = getDefaultLogger()
 	}
-	return &Logger{logger: logger}
+	return &Logger{
+		logger:   logger,
+		level:    newLevelControl(handlerMinLevel(logger.Handler())),
+		pipeline: &pipeline{},
+	}
+}
+
+// ContextWithLogger returns a copy of ctx carrying l. Package-level logging
+// functions use it in place of the global default.
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// LoggerFromContext returns the Logger stored by ContextWithLogger, or a Logger
+// wrapping the global default if there is none.
+func LoggerFromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerContextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return New(nil)
 }
 
 // NewContext initializes a new context with field accumulation support.
 // This must be called before using AddFields or any logging functions.
 func NewContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, fieldsContextKey, &fieldContainer{
+		start:    time.Now(),
 		fields:   make(map[string]any),
 		warnings: make([]Warning, 0),
 		errors:   make([]Warning, 0),
@@ -106,15 +172,7 @@ func AddWarning(ctx context.Context, message string, keysAndValues ...any) {
 		return
 	}
 
-	warning := Warning{Message: message}
-	if len(keysAndValues) > 0 {
-		warning.Fields = make(map[string]any)
-		for i := 0; i < len(keysAndValues)-1; i += 2 {
-			if key, ok := keysAndValues[i].(string); ok {
-				warning.Fields[key] = keysAndValues[i+1]
-			}
-		}
-	}
+	warning := Warning{Message: message, Fields: fieldMap(keysAndValues)}
 
 	container.mu.Lock()
 	container.warnings = append(container.warnings, warning)
@@ -129,21 +187,101 @@ func AddError(ctx context.Context, message string, keysAndValues ...any) {
 		return
 	}
 
-	errEntry := Warning{Message: message}
-	if len(keysAndValues) > 0 {
-		errEntry.Fields = make(map[string]any)
-		for i := 0; i < len(keysAndValues)-1; i += 2 {
-			if key, ok := keysAndValues[i].(string); ok {
-				errEntry.Fields[key] = keysAndValues[i+1]
-			}
+	errEntry := Warning{Message: message, Fields: fieldMap(keysAndValues)}
+
+	container.mu.Lock()
+	container.errors = append(container.errors, errEntry)
+	container.mu.Unlock()
+}
+
+// AddEvent records a timestamped event on the context's timeline.
+// The offset is measured from the call to NewContext, and the final log entry
+// carries the events in the order they were added.
+func AddEvent(ctx context.Context, name string, keysAndValues ...any) {
+	container := getContainer(ctx)
+	if container == nil {
+		getDefaultLogger().WarnContext(ctx, "widelogger: context not initialized", "func", "AddEvent")
+		return
+	}
+
+	event := TimelineEvent{Name: name, Fields: fieldMap(keysAndValues)}
+	limit := maxTimelineEvents.Load()
+
+	container.mu.Lock()
+	defer container.mu.Unlock()
+
+	if limit > 0 && int64(len(container.timeline)) >= limit {
+		container.timelineDropped++
+		return
+	}
+	event.OffsetMS = float64(time.Since(container.start).Microseconds()) / 1000
+	container.timeline = append(container.timeline, event)
+}
+
+// fieldMap converts key-value pairs to a map, skipping non-string keys.
+// It returns nil when there are no pairs.
+func fieldMap(keysAndValues []any) map[string]any {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+	fields := make(map[string]any)
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if key, ok := keysAndValues[i].(string); ok {
+			fields[key] = keysAndValues[i+1]
 		}
 	}
+	return fields
+}
 
+// ForceLog marks the context's event to be logged regardless of sampling.
+// It takes precedence over Suppress.
+func ForceLog(ctx context.Context) {
+	container := getContainer(ctx)
+	if container == nil {
+		getDefaultLogger().WarnContext(ctx, "widelogger: context not initialized", "func", "ForceLog")
+		return
+	}
 	container.mu.Lock()
-	container.errors = append(container.errors, errEntry)
+	container.forceLog = true
 	container.mu.Unlock()
 }
 
+// Suppress marks the context's events as noise so that events below LevelWarn
+// are not logged. The middleware's request event is still logged if the
+// Classifier puts it at LevelWarn or above.
+func Suppress(ctx context.Context) {
+	container := getContainer(ctx)
+	if container == nil {
+		getDefaultLogger().WarnContext(ctx, "widelogger: context not initialized", "func", "Suppress")
+		return
+	}
+	container.mu.Lock()
+	container.suppress = true
+	container.mu.Unlock()
+}
+
+// forceDebug makes every event of the context pass the Logger's minimum level
+// and sampling, as requested by a valid debug header.
+func forceDebug(ctx context.Context) {
+	if container := getContainer(ctx); container != nil {
+		container.mu.Lock()
+		container.forceLog = true
+		container.debug = true
+		container.mu.Unlock()
+	}
+}
+
+// logFlags returns the ForceLog, Suppress and debug flags of ctx.
+func logFlags(ctx context.Context) (forced, suppressed, debug bool) {
+	container := getContainer(ctx)
+	if container == nil {
+		return false, false, false
+	}
+	container.mu.Lock()
+	defer container.mu.Unlock()
+	return container.forceLog, container.suppress, container.debug
+}
+
 func HasWarnings(ctx context.Context) bool {
 	container := getContainer(ctx)
 	if container == nil {
@@ -164,6 +302,67 @@ func HasErrors(ctx context.Context) bool {
 	return len(container.errors) > 0
 }
 
+// Snapshot is a read-only copy of what a context has accumulated.
+type Snapshot struct {
+	fields   map[string]any
+	warnings []Warning
+	errors   []Warning
+	timeline []TimelineEvent
+}
+
+// GetSnapshot copies the fields, warnings, errors and timeline accumulated in ctx.
+// It returns an empty Snapshot if ctx was not initialized with NewContext.
+func GetSnapshot(ctx context.Context) Snapshot {
+	container := getContainer(ctx)
+	if container == nil {
+		return Snapshot{}
+	}
+
+	container.mu.Lock()
+	defer container.mu.Unlock()
+
+	snap := Snapshot{
+		fields:   make(map[string]any, len(container.fields)),
+		warnings: append([]Warning(nil), container.warnings...),
+		errors:   append([]Warning(nil), container.errors...),
+		timeline: append([]TimelineEvent(nil), container.timeline...),
+	}
+	for k, v := range container.fields {
+		snap.fields[k] = v
+	}
+	return snap
+}
+
+// Field returns the value of the field key.
+func (s Snapshot) Field(key string) (any, bool) {
+	v, ok := s.fields[key]
+	return v, ok
+}
+
+// Fields returns a copy of the accumulated fields.
+func (s Snapshot) Fields() map[string]any {
+	fields := make(map[string]any, len(s.fields))
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// Warnings returns the accumulated warnings.
+func (s Snapshot) Warnings() []Warning {
+	return append([]Warning(nil), s.warnings...)
+}
+
+// Errors returns the accumulated errors.
+func (s Snapshot) Errors() []Warning {
+	return append([]Warning(nil), s.errors...)
+}
+
+// Timeline returns the recorded timeline events.
+func (s Snapshot) Timeline() []TimelineEvent {
+	return append([]TimelineEvent(nil), s.timeline...)
+}
+
 func getContainer(ctx context.Context) *fieldContainer {
 	if ctx == nil {
 		return nil
@@ -172,8 +371,8 @@ func getContainer(ctx context.Context) *fieldContainer {
 	return container
 }
 
-// collectFields gathers all accumulated fields, warnings, and errors from the context.
-func collectFields(ctx context.Context) []any {
+// collectFields gathers all accumulated fields, warnings, errors, and timeline events from the context.
+func collectFields(ctx context.Context) map[string]any {
 	container := getContainer(ctx)
 	if container == nil {
 		return nil
@@ -182,39 +381,78 @@ func collectFields(ctx context.Context) []any {
 	container.mu.Lock()
 	defer container.mu.Unlock()
 
-	attrs := make([]any, 0, len(container.fields)*2+4)
+	fields := make(map[string]any, len(container.fields)+6)
 
 	for k, v := range container.fields {
-		attrs = append(attrs, k, v)
+		fields[k] = v
 	}
 
 	if len(container.warnings) > 0 {
-		attrs = append(attrs, "warnings", container.warnings)
-		attrs = append(attrs, "warning_count", len(container.warnings))
+		fields["warnings"] = container.warnings
+		fields["warning_count"] = len(container.warnings)
 	}
 
 	if len(container.errors) > 0 {
-		attrs = append(attrs, "errors", container.errors)
-		attrs = append(attrs, "error_count", len(container.errors))
+		fields["errors"] = container.errors
+		fields["error_count"] = len(container.errors)
+	}
+
+	if len(container.timeline) > 0 {
+		fields["timeline"] = container.timeline
+	}
+
+	if container.timelineDropped > 0 {
+		fields["timeline_dropped"] = container.timelineDropped
 	}
 
-	return attrs
+	return fields
 }
 
 // Log emits a log with accumulated context fields plus additional fields.
+// Additional fields override context fields with the same key. Events below
+// LevelWarn are subject to the Logger's Sampler, and registered processors run
+// before the event is written and may drop it.
 func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, additionalFields ...any) {
-	contextFields := collectFields(ctx)
+	l.log(ctx, level, msg, emitOptions{}, additionalFields)
+}
+
+// emitOptions adjusts how a single event is sampled.
+type emitOptions struct {
+	// sampler replaces the Logger's Sampler when set.
+	sampler Sampler
+	// annotate records sample_rate and sample_weight even if no sampler applies.
+	annotate bool
+}
+
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, opts emitOptions, additionalFields []any) {
+	if !l.Enabled(ctx, level) {
+		return
+	}
 
-	var allFields []any
-	if len(contextFields) > 0 {
-		allFields = make([]any, 0, len(contextFields)+len(additionalFields))
-		allFields = append(allFields, contextFields...)
-		allFields = append(allFields, additionalFields...)
-	} else {
-		allFields = additionalFields
+	sampler := opts.sampler
+	if sampler == nil {
+		sampler = l.loadSampler()
+	}
+	decision := sample(ctx, level, msg, sampler, additionalFields)
+	if !decision.Keep {
+		if s := l.pipeline.summary.Load(); s != nil {
+			s.record(ctx, level, snapshotWith(ctx, additionalFields))
+		}
+		return
+	}
+
+	event := newEvent(ctx, level, msg, additionalFields)
+	if sampler != nil || opts.annotate {
+		setSampleRate(event.Fields, decision.Rate)
+	}
+	setSampleRules(event.Fields, decision)
+	for _, p := range l.loadProcessors() {
+		if !p.Process(ctx, event) {
+			return
+		}
 	}
 
-	l.logger.Log(ctx, level, msg, allFields...)
+	l.logger.LogAttrs(ctx, event.Level, event.Message, event.attrs()...)
 }
 
 func (l *Logger) Info(ctx context.Context, msg string, additionalFields ...any) {
@@ -234,17 +472,17 @@ func (l *Logger) Debug(ctx context.Context, msg string, additionalFields ...any)
 }
 
 func Info(ctx context.Context, msg string, additionalFields ...any) {
-	New(nil).Info(ctx, msg, additionalFields...)
+	LoggerFromContext(ctx).Info(ctx, msg, additionalFields...)
 }
 
 func Error(ctx context.Context, msg string, additionalFields ...any) {
-	New(nil).Error(ctx, msg, additionalFields...)
+	LoggerFromContext(ctx).Error(ctx, msg, additionalFields...)
 }
 
 func Warn(ctx context.Context, msg string, additionalFields ...any) {
-	New(nil).Warn(ctx, msg, additionalFields...)
+	LoggerFromContext(ctx).Warn(ctx, msg, additionalFields...)
 }
 
 func Debug(ctx context.Context, msg string, additionalFields ...any) {
-	New(nil).Debug(ctx, msg, additionalFields...)
+	LoggerFromContext(ctx).Debug(ctx, msg, additionalFields...)
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewContext(t *testing.T) {
@@ -475,4 +476,95 @@ func TestSetDefaultLogger(t *testing.T) {
 		}()
 		SetDefaultLogger(nil)
 	})
-}
\ No newline at end of file
+}
+
+func TestAddEvent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := NewContext(context.Background())
+	AddEvent(ctx, "cache_miss", "key", "user:123")
+	time.Sleep(2 * time.Millisecond)
+	AddEvent(ctx, "db_query")
+
+	logger.Info(ctx, "request completed")
+
+	var result struct {
+		Timeline []TimelineEvent `json:"timeline"`
+	}
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+
+	if len(result.Timeline) != 2 {
+		t.Fatalf("Expected 2 timeline events, got %d", len(result.Timeline))
+	}
+	if result.Timeline[0].Name != "cache_miss" || result.Timeline[1].Name != "db_query" {
+		t.Errorf("Expected events in insertion order, got %+v", result.Timeline)
+	}
+	if result.Timeline[0].Fields["key"] != "user:123" {
+		t.Errorf("Expected event fields, got %v", result.Timeline[0].Fields)
+	}
+	if result.Timeline[1].OffsetMS < 2 || result.Timeline[1].OffsetMS < result.Timeline[0].OffsetMS {
+		t.Errorf("Expected increasing offsets relative to context start, got %+v", result.Timeline)
+	}
+}
+
+func TestAddEvent_Limit(t *testing.T) {
+	SetMaxTimelineEvents(2)
+	defer SetMaxTimelineEvents(100)
+
+	var buf bytes.Buffer
+	logger := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := NewContext(context.Background())
+	for i := 0; i < 5; i++ {
+		AddEvent(ctx, "retry", "attempt", i)
+	}
+	logger.Info(ctx, "request completed")
+
+	var result map[string]any
+	if err := json.NewDecoder(&buf).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if timeline, _ := result["timeline"].([]any); len(timeline) != 2 {
+		t.Errorf("Expected 2 timeline events, got %v", result["timeline"])
+	}
+	if result["timeline_dropped"].(float64) != 3 {
+		t.Errorf("Expected timeline_dropped=3, got %v", result["timeline_dropped"])
+	}
+}
+
+func TestAddEvent_UninitializedContext(t *testing.T) {
+	var buf bytes.Buffer
+	SetDefaultLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	AddEvent(context.Background(), "cache_miss")
+
+	if !strings.Contains(buf.String(), "context not initialized") {
+		t.Errorf("Expected warning for uninitialized context, got %q", buf.String())
+	}
+}
+
+func TestContextWithLogger(t *testing.T) {
+	var defaultBuf, ctxBuf bytes.Buffer
+	SetDefaultLogger(slog.New(slog.NewJSONHandler(&defaultBuf, nil)))
+	logger := New(slog.New(slog.NewJSONHandler(&ctxBuf, nil)))
+
+	if got := LoggerFromContext(context.Background()); got == nil || got.logger != getDefaultLogger() {
+		t.Error("Expected LoggerFromContext to fall back to the default logger")
+	}
+
+	ctx := ContextWithLogger(NewContext(context.Background()), logger)
+	if got := LoggerFromContext(ctx); got != logger {
+		t.Errorf("Expected LoggerFromContext to return the stored logger, got %v", got)
+	}
+
+	Info(ctx, "from context")
+	if !strings.Contains(ctxBuf.String(), "from context") {
+		t.Errorf("Expected package-level Info to use the context logger, got %q", ctxBuf.String())
+	}
+	if defaultBuf.Len() > 0 {
+		t.Errorf("Expected default logger to be unused, got %q", defaultBuf.String())
+	}
+}